@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+const (
+	// positionPenalty is added to a tracklist alignment score when
+	// the two tracks are not in the same place on their albums
+	positionPenalty = 0.05
+
+	// thresholdAlbum is the highest album score that is a match
+	thresholdAlbum = 0.25
+
+	// minAlbumTracks is the fewest library tracks that are worth
+	// matching as an album rather than one at a time
+	minAlbumTracks = 3
+
+	// maxAlbumCandidates is the most spotify albums whose
+	// tracklists are fetched for a single library album
+	maxAlbumCandidates = 3
+)
+
+// LibraryAlbum is a group of library tracks from the same album
+type LibraryAlbum struct {
+	Artist string
+	Name   string
+	Tracks []*itunes.Track
+}
+
+// albumArtist is the artist that the album of a track is credited to
+func albumArtist(track *itunes.Track) string {
+	if track.AlbumArtist != "" {
+		return track.AlbumArtist
+	}
+	return track.Artist
+}
+
+// AlbumKey identifies the album of a track by its album artist, name and
+// year, so that albums with the same name by different artists (or
+// self titled albums) are kept apart
+func AlbumKey(track *itunes.Track) string {
+	year := ""
+	if track.Year != 0 {
+		year = fmt.Sprintf("%d", track.Year)
+	}
+	return normalizeName(albumArtist(track)) + "|" + normalizeName(track.Album) + "|" + year
+}
+
+// albumHasArtist checks if any of the album tracks is by the
+// artist of the goal, which a cached album must be to be used
+func albumHasArtist(goal *itunes.Track, tracks []spotify.FullTrack, transliterateNames bool) bool {
+
+	for j := range tracks {
+		if activeAliases.MatchesArtist(goal.Artist, &tracks[j]) {
+			return true
+		}
+		for _, a := range tracks[j].Artists {
+			if score, _, _ := artistCompare(goal.Artist, a.Name, transliterateNames); score <= thresholdLikely {
+				return true
+			}
+		}
+		if score, _, _ := artistCompare(goal.Artist, artist(&tracks[j]), transliterateNames); score <= thresholdLikely {
+			return true
+		}
+	}
+	return false
+
+}
+
+// GroupAlbums groups the given tracks by album, in the order
+// that the albums first appear and skipping tracks that have no album
+func GroupAlbums(tracks []*itunes.Track) []*LibraryAlbum {
+
+	var albums []*LibraryAlbum
+	byKey := make(map[string]*LibraryAlbum)
+	for _, track := range tracks {
+		if track.Album == "" {
+			continue
+		}
+		key := AlbumKey(track)
+		album, ok := byKey[key]
+		if !ok {
+			album = &LibraryAlbum{
+				Artist: albumArtist(track),
+				Name:   track.Album,
+			}
+			byKey[key] = album
+			albums = append(albums, album)
+		}
+		album.Tracks = append(album.Tracks, track)
+	}
+	return albums
+
+}
+
+// AlbumSearchAttempts returns a list of strings to
+// try in searching for this album, most specific first
+func AlbumSearchAttempts(album *LibraryAlbum) []string {
+
+	name := strings.ToLower(normalizeText(activeAliases.AlbumName(album.Name)))
+	artist := strings.ToLower(normalizeText(activeAliases.ArtistName(album.Artist)))
+
+	var queries []string
+	if !isVariousArtists(album.Artist) {
+		queries = append(queries,
+			fmt.Sprintf(`album:"%s" artist:"%s"`, name, artist),
+			fmt.Sprintf(`"%s" "%s"`, name, artist),
+		)
+	}
+	queries = append(queries, fmt.Sprintf(`album:"%s"`, name))
+	return queries
+
+}
+
+// ScoreAlbum scores how well a spotify tracklist lines up with the
+// library tracks of an album from the aligned pairs, where 0 is a perfect
+// match. Every library track is expected to be found, in the same place,
+// with the same duration and without many extra tracks on spotify, where
+// library tracks without a track number have no place to be found in
+func ScoreAlbum(goals []*itunes.Track, tracks []spotify.FullTrack, aligned []*MatchedTrack) float64 {
+
+	if len(goals) == 0 || len(aligned) == 0 {
+		return 1
+	}
+
+	var trackScore, moved, duration float64
+	timed, numbered := 0, 0
+	for _, mt := range aligned {
+		trackScore += mt.score
+		if mt.itunes.TrackNumber != 0 {
+			if !samePosition(mt.itunes, mt.spotify) {
+				moved++
+			}
+			numbered++
+		}
+		if mt.itunes.TotalTime > 0 {
+			diff := math.Abs(float64(mt.spotify.Duration - mt.itunes.TotalTime))
+			duration += math.Min(1, diff/10000)
+			timed++
+		}
+	}
+	trackScore /= float64(len(aligned))
+	if numbered > 0 {
+		moved /= float64(numbered)
+	}
+	if timed > 0 {
+		duration /= float64(timed)
+	}
+
+	unmatched := 1 - float64(len(aligned))/float64(len(goals))
+	extra := math.Abs(float64(len(tracks)-len(goals))) / math.Max(float64(len(tracks)), float64(len(goals)))
+
+	return 0.4*unmatched + 0.2*trackScore/thresholdMatched + 0.15*moved + 0.1*extra + 0.15*duration
+
+}
+
+// variousArtists are the album artist names used for compilations
+var variousArtists = []string{
+	"various artists",
+	"various",
+	"va",
+	"v.a.",
+	"verschiedene interpreten",
+	"artistes divers",
+	"varios artistas",
+	"artisti vari",
+}
+
+// isVariousArtists checks if the given album artist
+// is one of the names used for compilations
+func isVariousArtists(albumArtist string) bool {
+	return StringInSlice(normalizeName(albumArtist), variousArtists)
+}
+
+// isCompilation checks if the track is on a compilation album
+func isCompilation(track *itunes.Track) bool {
+	return track.Compilation || isVariousArtists(track.AlbumArtist)
+}
+
+// sameAlbum checks if the two library tracks are on the same album
+func sameAlbum(a, b *itunes.Track) bool {
+	return a.Album != "" &&
+		strings.EqualFold(a.Album, b.Album) &&
+		strings.EqualFold(a.AlbumArtist, b.AlbumArtist)
+}
+
+// AlignTracklist pairs the library tracks of an album with the tracks of a
+// spotify album so that the whole album can be matched at once, preferring
+// tracks in the same place on both. Each spotify track is used at most once
+// and only pairs within the match threshold are returned. The options
+// are given for each library track, since they depend on its metadata
+func AlignTracklist(goals []*itunes.Track, tracks []spotify.FullTrack, options func(goal *itunes.Track) CompareOptions) []*MatchedTrack {
+
+	var pairs []*MatchedTrack
+	for _, goal := range goals {
+		// the album was already chosen, so only the tracks matter
+		opts := options(goal)
+		opts.IgnoreAlbum = true
+		for j := range tracks {
+			sb := CompareTracks(goal, &tracks[j], opts)
+			// the tracklist position plays the part of the search rank
+			if goal.TrackNumber != 0 && !samePosition(goal, &tracks[j]) {
+				sb.Rank = positionPenalty
+			}
+			pairs = append(pairs, &MatchedTrack{
+				itunes:    goal,
+				spotify:   &tracks[j],
+				score:     sb.Total(),
+				breakdown: sb,
+			})
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return pairs[a].score < pairs[b].score
+	})
+
+	usedGoals := make(map[int]bool)
+	usedTracks := make(map[spotify.ID]bool)
+	var aligned []*MatchedTrack
+	for _, mt := range pairs {
+		if mt.score > thresholdMatched {
+			break
+		}
+		if usedGoals[mt.itunes.TrackID] || usedTracks[mt.spotify.ID] {
+			continue
+		}
+		usedGoals[mt.itunes.TrackID] = true
+		usedTracks[mt.spotify.ID] = true
+		aligned = append(aligned, mt)
+	}
+	return aligned
+
+}
+
+// samePosition checks if the tracks have the same disc and track number,
+// where a library track without a disc number is on the first disc
+func samePosition(goal *itunes.Track, test *spotify.FullTrack) bool {
+
+	disc := goal.DiscNumber
+	if disc == 0 {
+		disc = 1
+	}
+	return goal.TrackNumber != 0 &&
+		goal.TrackNumber == test.TrackNumber &&
+		disc == test.DiscNumber
+
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// testAlbum builds a library album and the same album on spotify
+func testAlbum(names ...string) ([]*itunes.Track, []spotify.FullTrack) {
+
+	var goals []*itunes.Track
+	var tracks []spotify.FullTrack
+	for j, name := range names {
+		goals = append(goals, &itunes.Track{
+			TrackID:     j + 1,
+			Name:        name,
+			Artist:      "the band",
+			Album:       "first album",
+			TrackNumber: j + 1,
+			TotalTime:   200000,
+		})
+		test := spotify.FullTrack{}
+		test.ID = spotify.ID(name)
+		test.Name = name
+		test.Artists = []spotify.SimpleArtist{{Name: "the band"}}
+		test.Album.Name = "first album"
+		test.TrackNumber = j + 1
+		test.DiscNumber = 1
+		test.Duration = 200000
+		tracks = append(tracks, test)
+	}
+	return goals, tracks
+
+}
+
+func noOptions(goal *itunes.Track) CompareOptions {
+	return CompareOptions{}
+}
+
+func TestGroupAlbums(t *testing.T) {
+
+	tracks := []*itunes.Track{
+		{Name: "a", Artist: "the band", Album: "Self Titled", Year: 2001},
+		{Name: "b", Artist: "other band", Album: "Self Titled", Year: 2001},
+		{Name: "c", Artist: "The Band", Album: "self titled", Year: 2001},
+		{Name: "d", Artist: "the band", Album: ""},
+		{Name: "e", Artist: "someone", AlbumArtist: "the band", Album: "Self Titled", Year: 2001},
+	}
+	albums := GroupAlbums(tracks)
+	if len(albums) != 2 {
+		t.Fatalf("expected 2 albums, got %d", len(albums))
+	}
+	if len(albums[0].Tracks) != 3 || albums[0].Artist != "the band" {
+		t.Errorf("expected the band to have 3 tracks by album artist, got %d", len(albums[0].Tracks))
+	}
+	if len(albums[1].Tracks) != 1 || albums[1].Artist != "other band" {
+		t.Error("expected an album with the same name by another artist to be kept apart")
+	}
+
+}
+
+func TestAlignTracklist(t *testing.T) {
+
+	goals, tracks := testAlbum("first song", "second song", "third song")
+
+	// the spotify album is in a different order with an extra track
+	extra := spotify.FullTrack{}
+	extra.ID = "bonus"
+	extra.Name = "bonus song"
+	extra.Artists = tracks[0].Artists
+	shuffled := []spotify.FullTrack{tracks[2], extra, tracks[0], tracks[1]}
+
+	aligned := AlignTracklist(goals, shuffled, noOptions)
+	if len(aligned) != 3 {
+		t.Fatalf("expected every library track to be aligned, got %d", len(aligned))
+	}
+	for _, mt := range aligned {
+		if mt.spotify.Name != mt.itunes.Name {
+			t.Errorf("expected %s to be aligned with itself, got %s", mt.itunes.Name, mt.spotify.Name)
+		}
+	}
+
+	// each spotify track can only be used once
+	aligned = AlignTracklist(append(goals, goals[0]), tracks[:1], noOptions)
+	if len(aligned) != 1 {
+		t.Errorf("expected a spotify track to be aligned once, got %d", len(aligned))
+	}
+
+}
+
+func TestScoreAlbum(t *testing.T) {
+
+	goals, tracks := testAlbum("first song", "second song", "third song", "fourth song")
+
+	perfect := ScoreAlbum(goals, tracks, AlignTracklist(goals, tracks, noOptions))
+	if perfect > thresholdAlbum {
+		t.Errorf("expected the same album to line up, got %f", perfect)
+	}
+
+	partial := ScoreAlbum(goals, tracks[:2], AlignTracklist(goals, tracks[:2], noOptions))
+	if partial <= perfect {
+		t.Errorf("expected missing tracks to score worse (%f) than a full album (%f)", partial, perfect)
+	}
+
+	if ScoreAlbum(goals, tracks, nil) != 1 {
+		t.Error("expected an album without aligned tracks to score 1")
+	}
+
+}
+
+func TestUnnumberedAlbum(t *testing.T) {
+
+	// playlist and csv libraries have no track numbers
+	goals, tracks := testAlbum("first song", "second song", "third song", "fourth song")
+	numbered := ScoreAlbum(goals, tracks, AlignTracklist(goals, tracks, noOptions))
+	for _, goal := range goals {
+		goal.TrackNumber = 0
+	}
+
+	aligned := AlignTracklist(goals, tracks, noOptions)
+	if len(aligned) != len(goals) {
+		t.Fatalf("expected every library track to be aligned, got %d", len(aligned))
+	}
+	for _, mt := range aligned {
+		if mt.breakdown.Rank != 0 {
+			t.Errorf("expected no position penalty for %s without a track number, got %f", mt.itunes.Name, mt.breakdown.Rank)
+		}
+	}
+
+	score := ScoreAlbum(goals, tracks, aligned)
+	if score != numbered {
+		t.Errorf("expected an unnumbered album to score as if in place (%f), got %f", numbered, score)
+	}
+	if score > thresholdAlbum {
+		t.Errorf("expected the unnumbered album to line up, got %f", score)
+	}
+
+}
+
+func TestIsVariousArtists(t *testing.T) {
+
+	tests := map[string]bool{
+		"Various Artists":          true,
+		"VARIOUS":                  true,
+		"V.A.":                     true,
+		"Verschiedene Interpreten": true,
+		"The Band":                 false,
+		"Various Artists Band":     false,
+		"":                         false,
+	}
+
+	for name, expected := range tests {
+		if actual := isVariousArtists(name); actual != expected {
+			t.Errorf("expected %q various artists to be %v", name, expected)
+		}
+	}
+
+	if !isCompilation(&itunes.Track{AlbumArtist: "The Band", Compilation: true}) {
+		t.Error("expected a track marked as a compilation to be on one")
+	}
+
+}
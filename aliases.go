@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+// Alias is the spotify name and/or id for a name used in the library
+type Alias struct {
+	Name string `json:",omitempty"`
+	ID   string `json:",omitempty"`
+}
+
+// Aliases map artist and album names that are consistently named
+// differently in the library to their names or ids on spotify, eg:
+//
+//	{
+//	  "Artists": {
+//	    "Beyonce": {"Name": "Beyoncé"},
+//	    "Prince & The Revolution": {"Name": "Prince", "ID": "5a2EaR3hamoenG9rDuVn8j"}
+//	  },
+//	  "Albums": {
+//	    "Purple Rain OST": {"ID": "7nXJ5k4XgRj5OLg9m8V3zc"}
+//	  }
+//	}
+type Aliases struct {
+	Artists map[string]Alias
+	Albums  map[string]Alias
+
+	// lookups by lowercased library or spotify name
+	artists map[string]Alias
+	albums  map[string]Alias
+}
+
+// activeAliases are the aliases used when matching tracks
+var activeAliases = &Aliases{}
+
+// artistSplit separates the artists in a combined artist name
+var artistSplit = regexp.MustCompile(`\s*(&|,|/)\s*`)
+
+// LoadAliases loads the alias file kept next to the given library,
+// returning no aliases if there is none
+func LoadAliases(libraryPath string) (*Aliases, error) {
+
+	aliases := &Aliases{}
+	jsonData, err := ioutil.ReadFile(itspFile(libraryPath, "aliases"))
+	if os.IsNotExist(err) {
+		return aliases, nil
+	} else if nil != err {
+		return aliases, err
+	}
+	if err = json.Unmarshal(jsonData, aliases); nil != err {
+		return &Aliases{}, fmt.Errorf("error reading aliases: %s", err)
+	}
+	aliases.index()
+	return aliases, nil
+
+}
+
+func (a *Aliases) index() {
+
+	a.artists = make(map[string]Alias)
+	for name, alias := range a.Artists {
+		a.artists[aliasKey(name)] = alias
+	}
+	a.albums = make(map[string]Alias)
+	for name, alias := range a.Albums {
+		a.albums[aliasKey(name)] = alias
+	}
+
+	// the spotify names are looked up too, so that an alias
+	// still applies to a name that has already been replaced
+	for _, alias := range a.Artists {
+		if _, ok := a.artists[aliasKey(alias.Name)]; !ok && alias.Name != "" {
+			a.artists[aliasKey(alias.Name)] = alias
+		}
+	}
+	for _, alias := range a.Albums {
+		if _, ok := a.albums[aliasKey(alias.Name)]; !ok && alias.Name != "" {
+			a.albums[aliasKey(alias.Name)] = alias
+		}
+	}
+
+}
+
+func aliasKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Artist returns the alias for the given artist, if any
+func (a *Aliases) Artist(name string) (Alias, bool) {
+	alias, ok := a.artists[aliasKey(name)]
+	return alias, ok
+}
+
+// Album returns the alias for the given album, if any
+func (a *Aliases) Album(name string) (Alias, bool) {
+	alias, ok := a.albums[aliasKey(name)]
+	return alias, ok
+}
+
+// ArtistName replaces an artist name with its spotify name, checking
+// the full name first and then each of the artists within it
+func (a *Aliases) ArtistName(name string) string {
+
+	if alias, ok := a.Artist(name); ok && alias.Name != "" {
+		return alias.Name
+	}
+	if len(a.artists) == 0 {
+		return name
+	}
+
+	parts := artistSplit.Split(name, -1)
+	if len(parts) < 2 {
+		return name
+	}
+	changed := false
+	for j, part := range parts {
+		if alias, ok := a.Artist(part); ok && alias.Name != "" {
+			parts[j] = alias.Name
+			changed = true
+		}
+	}
+	if !changed {
+		return name
+	}
+	return strings.Join(parts, " & ")
+
+}
+
+// AlbumName replaces an album name with its spotify name
+func (a *Aliases) AlbumName(name string) string {
+	if alias, ok := a.Album(name); ok && alias.Name != "" {
+		return alias.Name
+	}
+	return name
+}
+
+// ArtistIDs collects the spotify artist ids that the
+// artists within the given name are mapped to
+func (a *Aliases) ArtistIDs(name string) []spotify.ID {
+
+	var ids []spotify.ID
+	seen := make(map[string]bool)
+	for _, part := range append([]string{name}, artistSplit.Split(name, -1)...) {
+		if alias, ok := a.Artist(part); ok && alias.ID != "" && !seen[alias.ID] {
+			ids = append(ids, spotify.ID(alias.ID))
+			seen[alias.ID] = true
+		}
+	}
+	return ids
+
+}
+
+// MatchesArtist checks if any of the test track artists is
+// one that the given library artist is mapped to by id
+func (a *Aliases) MatchesArtist(name string, test *spotify.FullTrack) bool {
+
+	for _, id := range a.ArtistIDs(name) {
+		for _, artist := range test.Artists {
+			if artist.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+
+}
+
+// MatchesAlbum checks if the test track is on the album
+// that the given library album is mapped to by id
+func (a *Aliases) MatchesAlbum(name string, test *spotify.FullTrack) bool {
+	alias, ok := a.Album(name)
+	return ok && alias.ID != "" && spotify.ID(alias.ID) == test.Album.ID
+}
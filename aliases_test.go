@@ -0,0 +1,180 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+func testAliases() *Aliases {
+	aliases := &Aliases{
+		Artists: map[string]Alias{
+			"Beyonce":                 {Name: "Beyoncé"},
+			"Prince & The Revolution": {Name: "Prince", ID: "prince"},
+			"Jay Z":                   {Name: "JAY-Z", ID: "jayz"},
+			"The Band":                {ID: "band"},
+		},
+		Albums: map[string]Alias{
+			"Purple Rain OST":   {ID: "purplerain"},
+			"Lemonade (Deluxe)": {Name: "Lemonade"},
+		},
+	}
+	aliases.index()
+	return aliases
+}
+
+func TestAliasLookup(t *testing.T) {
+
+	aliases := testAliases()
+
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"Beyonce", "Beyoncé", true},
+		{" beyonce ", "Beyoncé", true},
+		{"Beyoncé", "Beyoncé", true}, // the spotify name maps to itself
+		{"PRINCE & THE REVOLUTION", "Prince", true},
+		{"Prince", "Prince", true},
+		{"The Band", "", true},
+		{"Somebody", "", false},
+	}
+
+	for _, test := range tests {
+		alias, ok := aliases.Artist(test.name)
+		if ok != test.ok || alias.Name != test.expected {
+			t.Errorf("expected %q, %v for %q, got %q, %v", test.expected, test.ok, test.name, alias.Name, ok)
+		}
+	}
+
+	if alias, ok := aliases.Album("purple rain ost"); !ok || alias.ID != "purplerain" {
+		t.Errorf("expected the album alias to be found, got %+v, %v", alias, ok)
+	}
+	if _, ok := (&Aliases{}).Artist("Beyonce"); ok {
+		t.Errorf("expected no alias from empty aliases")
+	}
+
+}
+
+func TestAliasNames(t *testing.T) {
+
+	aliases := testAliases()
+
+	artists := map[string]string{
+		"Beyonce":                           "Beyoncé",
+		"Prince & The Revolution":           "Prince",
+		"Beyonce & Jay Z":                   "Beyoncé & JAY-Z",
+		"Beyonce, Someone/Jay Z":            "Beyoncé & Someone & JAY-Z",
+		"Someone, Somebody":                 "Someone, Somebody",
+		"The Band & Beyonce":                "The Band & Beyoncé",
+		"The Band":                          "The Band",
+		"Prince & The Revolution & Beyonce": "Prince & The Revolution & Beyoncé",
+	}
+	for name, expected := range artists {
+		if replaced := aliases.ArtistName(name); replaced != expected {
+			t.Errorf("expected artist %q for %q, got %q", expected, name, replaced)
+		}
+	}
+	if name := (&Aliases{}).ArtistName("Beyonce & Jay Z"); name != "Beyonce & Jay Z" {
+		t.Errorf("expected empty aliases to keep the name, got %q", name)
+	}
+
+	albums := map[string]string{
+		"Lemonade (Deluxe)": "Lemonade",
+		"Purple Rain OST":   "Purple Rain OST",
+		"Other":             "Other",
+	}
+	for name, expected := range albums {
+		if replaced := aliases.AlbumName(name); replaced != expected {
+			t.Errorf("expected album %q for %q, got %q", expected, name, replaced)
+		}
+	}
+
+}
+
+func TestAliasIDs(t *testing.T) {
+
+	aliases := testAliases()
+
+	tests := []struct {
+		name     string
+		expected []spotify.ID
+	}{
+		{"Prince & The Revolution", []spotify.ID{"prince"}},
+		{"The Band", []spotify.ID{"band"}},
+		{"Jay Z / The Band", []spotify.ID{"jayz", "band"}},
+		{"Beyonce", nil},
+		{"Somebody", nil},
+	}
+
+	for _, test := range tests {
+		ids := aliases.ArtistIDs(test.name)
+		if len(ids) != len(test.expected) {
+			t.Errorf("expected ids %v for %q, got %v", test.expected, test.name, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != test.expected[i] {
+				t.Errorf("expected ids %v for %q, got %v", test.expected, test.name, ids)
+				break
+			}
+		}
+	}
+
+	track := &spotify.FullTrack{}
+	track.Artists = []spotify.SimpleArtist{{Name: "Other", ID: "other"}, {Name: "The Band", ID: "band"}}
+	track.Album.ID = "purplerain"
+
+	if !aliases.MatchesArtist("Somebody & The Band", track) {
+		t.Errorf("expected an artist mapped by id to match")
+	}
+	if aliases.MatchesArtist("Jay Z", track) || aliases.MatchesArtist("Beyonce", track) {
+		t.Errorf("expected artists mapped to other ids, or only by name, not to match")
+	}
+	if !aliases.MatchesAlbum("Purple Rain OST", track) {
+		t.Errorf("expected an album mapped by id to match")
+	}
+	if aliases.MatchesAlbum("Lemonade (Deluxe)", track) || aliases.MatchesAlbum("Other", track) {
+		t.Errorf("expected albums without a matching id not to match")
+	}
+
+}
+
+func TestLoadAliases(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	libraryPath := filepath.Join(dir, "Library.xml")
+
+	aliases, err := LoadAliases(libraryPath)
+	if nil != err || aliases.ArtistName("Beyonce") != "Beyonce" {
+		t.Errorf("expected no aliases without an alias file, got %+v, %v", aliases, err)
+	}
+
+	path := itspFile(libraryPath, "aliases")
+	if err = ioutil.WriteFile(path, []byte(`{"Artists": {"Beyonce": {"Name": "Beyoncé"}}}`), 0644); nil != err {
+		t.Fatal(err)
+	}
+	aliases, err = LoadAliases(libraryPath)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if name := aliases.ArtistName("beyonce"); name != "Beyoncé" {
+		t.Errorf("expected the loaded aliases to be indexed, got %q", name)
+	}
+
+	if err = ioutil.WriteFile(path, []byte(`{"Artists": [`), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if _, err = LoadAliases(libraryPath); nil == err {
+		t.Errorf("expected an error for invalid json")
+	}
+
+}
@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 
 	itunes "github.com/rydrman/go-itunes-library"
 	"github.com/zmb3/spotify"
 )
 
+// matchCacheVersion is the current layout of the match cache, where
+// version 2 keys albums by AlbumKey rather than just their name
+const matchCacheVersion = 2
+
 // MatchCache is a chache to hold previously mapped track and album data
 type MatchCache struct {
+	Version     int
 	LibraryFile string
 	CacheFile   string
 	TrackMap    TrackMap
@@ -23,9 +27,7 @@ type MatchCache struct {
 // but will return an empty cache if not found
 func InitMatchCache(itunesLibraryPath string) *MatchCache {
 
-	ext := path.Ext(itunesLibraryPath)
-	baseName := itunesLibraryPath[0 : len(itunesLibraryPath)-len(ext)]
-	cacheFile := fmt.Sprintf("%s.itsp.cache", baseName)
+	cacheFile := itspFile(itunesLibraryPath, "cache")
 
 	cache := &MatchCache{
 		LibraryFile: itunesLibraryPath,
@@ -36,6 +38,7 @@ func InitMatchCache(itunesLibraryPath string) *MatchCache {
 
 	if _, err := os.Open(cacheFile); os.IsNotExist(err) {
 		fmt.Printf("no cache found for: %s\n", cache.LibraryFile)
+		cache.Version = matchCacheVersion
 		return cache
 	}
 
@@ -54,6 +57,40 @@ func InitMatchCache(itunesLibraryPath string) *MatchCache {
 
 }
 
+// MigrateAlbums moves albums cached by an older version onto their current
+// keys using the library they were matched from, dropping any album name
+// that is used by more than one album since it may be the wrong one
+func (mc *MatchCache) MigrateAlbums(lib *Library) {
+
+	if mc.Version >= matchCacheVersion {
+		return
+	}
+
+	keys := make(map[string][]string)
+	for _, track := range lib.Tracks {
+		key := AlbumKey(track)
+		if !StringInSlice(key, keys[track.Album]) {
+			keys[track.Album] = append(keys[track.Album], key)
+		}
+	}
+
+	albums := make(AlbumMap)
+	dropped := 0
+	for name, match := range mc.AlbumMap {
+		if len(keys[name]) != 1 {
+			dropped++
+			continue
+		}
+		albums[keys[name][0]] = match
+	}
+
+	fmt.Printf("migrated %d cached albums, dropped %d ambiguous ones\n", len(albums), dropped)
+	mc.AlbumMap = albums
+	mc.Version = matchCacheVersion
+	mc.SaveCache()
+
+}
+
 // SaveCache saves this cache to the file system based on the library that
 // it was initialized for (overwriting existing cache is it exists)
 func (mc *MatchCache) SaveCache() error {
@@ -78,6 +115,7 @@ type CachedTrackMatch struct {
 	ItunesID           int
 	ItunesPersistentID string
 	Score              float64
+	Breakdown          *ScoreBreakdown `json:",omitempty"`
 }
 
 // TrackMap stores simple id mapping data for itunes to spotify mappings
@@ -90,8 +128,9 @@ func (tm *TrackMap) GetMatch(goal *itunes.Track) *MatchedTrack {
 	if cached, ok := (*tm)[goal.PersistentID]; ok {
 
 		mt := &MatchedTrack{
-			itunes: goal,
-			score:  cached.Score,
+			itunes:    goal,
+			score:     cached.Score,
+			breakdown: cached.Breakdown,
 		}
 
 		if cached.SpotifyID == "" {
@@ -131,6 +170,7 @@ func (tm *TrackMap) Store(mt *MatchedTrack) {
 		ItunesID:           mt.itunes.TrackID,
 		ItunesPersistentID: mt.itunes.PersistentID,
 		Score:              mt.score,
+		Breakdown:          mt.breakdown,
 	}
 
 }
@@ -147,11 +187,11 @@ type CachedAlbumMatch struct {
 // AlbumMap stores simple id mapping data for itunes to spotify mappings
 type AlbumMap map[string]CachedAlbumMatch
 
-// GetMatch tried to build a mapped track instance for the given
-// itunes track if it is available in this map
-func (am *AlbumMap) GetMatch(name string) *spotify.FullAlbum {
+// GetMatch fetches the spotify album cached under
+// the given album key if it is available in this map
+func (am *AlbumMap) GetMatch(key string) *spotify.FullAlbum {
 
-	if cached, ok := (*am)[name]; ok {
+	if cached, ok := (*am)[key]; ok {
 
 		for {
 			res, err := Session.Client().GetAlbum(spotify.ID(cached.SpotifyID))
@@ -170,10 +210,10 @@ func (am *AlbumMap) GetMatch(name string) *spotify.FullAlbum {
 	return nil
 }
 
-// Store stores the given mapping in this map
-func (am *AlbumMap) Store(mt *MatchedTrack) {
+// Store stores the album of the given match under the album key
+func (am *AlbumMap) Store(key string, mt *MatchedTrack) {
 
-	(*am)[mt.itunes.Album] = CachedAlbumMatch{
+	(*am)[key] = CachedAlbumMatch{
 		SpotifyAlbum: mt.spotify.Album.Name,
 		SpotifyID:    mt.spotify.Album.ID.String(),
 		Score:        mt.score,
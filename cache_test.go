@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+func TestMigrateAlbums(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	libraryPath := filepath.Join(dir, "Library.xml")
+
+	tracks := []*itunes.Track{
+		{Name: "One", Artist: "Band", Album: "Unique", Year: 2001},
+		{Name: "Two", Artist: "Band", Album: "Unique", Year: 2001},
+		{Name: "Three", Artist: "Band", Album: "Greatest Hits", Year: 2005},
+		{Name: "Four", Artist: "Other Band", Album: "Greatest Hits", Year: 1999},
+		{Name: "Five", Artist: "Singer", AlbumArtist: "Various Artists", Album: "Mixed"},
+		{Name: "Six", Artist: "Other Singer", AlbumArtist: "Various Artists", Album: "Mixed"},
+	}
+	lib := &Library{Library: &itunes.Library{Tracks: tracks}}
+
+	cache := &MatchCache{
+		Version:   1,
+		CacheFile: itspFile(libraryPath, "cache"),
+		TrackMap:  make(TrackMap),
+		AlbumMap: AlbumMap{
+			"Unique":        {SpotifyAlbum: "Unique", SpotifyID: "unique"},
+			"Greatest Hits": {SpotifyAlbum: "Greatest Hits", SpotifyID: "hits"},
+			"Mixed":         {SpotifyAlbum: "Mixed", SpotifyID: "mixed"},
+			"Removed":       {SpotifyAlbum: "Removed", SpotifyID: "removed"},
+		},
+	}
+	cache.MigrateAlbums(lib)
+
+	expected := map[string]string{
+		AlbumKey(tracks[0]): "unique",
+		AlbumKey(tracks[4]): "mixed",
+	}
+	if len(cache.AlbumMap) != len(expected) {
+		t.Errorf("expected %d migrated albums, got %v", len(expected), cache.AlbumMap)
+	}
+	for key, id := range expected {
+		if cache.AlbumMap[key].SpotifyID != id {
+			t.Errorf("expected %s to be migrated to %q, got %+v", id, key, cache.AlbumMap[key])
+		}
+	}
+	if cache.Version != matchCacheVersion {
+		t.Errorf("expected the cache to be at version %d, got %d", matchCacheVersion, cache.Version)
+	}
+
+	// the migrated cache is saved and is not migrated again
+	if _, err := os.Stat(cache.CacheFile); nil != err {
+		t.Errorf("expected the migrated cache to be saved: %v", err)
+	}
+	cache.AlbumMap["Unique"] = CachedAlbumMatch{SpotifyID: "name"}
+	cache.MigrateAlbums(lib)
+	if cache.AlbumMap["Unique"].SpotifyID != "name" || len(cache.AlbumMap) != len(expected)+1 {
+		t.Errorf("expected a current cache not to be migrated, got %v", cache.AlbumMap)
+	}
+
+}
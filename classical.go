@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	re "regexp"
+	"strconv"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// ClassicalInfo is the classical metadata of a library track, used
+// to compare it by composer, work and movement rather than by the
+// title and artist, which are named very differently between catalogues
+type ClassicalInfo struct {
+	Composer       string
+	Conductor      string
+	Work           string
+	MovementName   string
+	MovementNumber int
+}
+
+// NewClassicalInfo collects the classical metadata of the given track,
+// returning nil if it does not look like a classical recording
+func NewClassicalInfo(goal *itunes.Track, extras *TrackExtras) *ClassicalInfo {
+
+	if goal.Composer == "" {
+		return nil
+	}
+	if extras.Work == "" && !strings.Contains(strings.ToLower(goal.Genre), "classical") {
+		return nil
+	}
+
+	return &ClassicalInfo{
+		Composer:       goal.Composer,
+		Conductor:      extras.Conductor,
+		Work:           extras.Work,
+		MovementName:   extras.MovementName,
+		MovementNumber: extras.MovementNumber,
+	}
+
+}
+
+// catalogueRe finds catalogue numbers like BWV 1007, K. 525, Op. 27 No. 2 and Op. 27/2
+var catalogueRe = re.MustCompile(
+	`(?i)(?:^|[\s,(\[])(bwv|kv|k|op|d|hob|hwv|rv|sz|woo|s)\.?\s*([0-9]+[a-z]?)(?:\s*(?:,?\s*no\.?|/)\s*([0-9]+))?`)
+
+// catalogueNumber is a catalogue number in a normalized form for
+// comparison, without its sub-number as the base, and as it was
+// written for searching
+type catalogueNumber struct {
+	Key  string
+	Base string
+	Text string
+}
+
+func catalogueNumbers(s string) []catalogueNumber {
+
+	var numbers []catalogueNumber
+	for _, groups := range catalogueRe.FindAllStringSubmatch(s, -1) {
+		prefix := strings.ToLower(groups[1])
+		if prefix == "kv" {
+			prefix = "k"
+		}
+		base := prefix + strings.ToLower(groups[2])
+		key := base
+		if groups[3] != "" {
+			key += "/" + groups[3]
+		}
+		numbers = append(numbers, catalogueNumber{
+			Key:  key,
+			Base: base,
+			Text: strings.TrimLeft(groups[0], " ,([\t"),
+		})
+	}
+	return numbers
+
+}
+
+// sameCatalogue checks if any catalogue number appears in both lists,
+// where a number without a sub-number could be any of the work's parts,
+// eg: Op. 27 is the same work as Op. 27 No. 2
+func sameCatalogue(a, b []catalogueNumber) bool {
+
+	for _, ca := range a {
+		for _, cb := range b {
+			if ca.Key == cb.Key {
+				return true
+			}
+			if ca.Base == cb.Base && (ca.Key == ca.Base || cb.Key == cb.Base) {
+				return true
+			}
+		}
+	}
+	return false
+
+}
+
+// titleRejection is the title score of a title that cannot match,
+// which is the whole of the weighted score of the active profile
+func titleRejection() float64 {
+	return rejection(activeProfile.TitleWeight, titleWeight)
+}
+
+// rejection is the score of a comparison that cannot match, which
+// is the whole of the weighted score for the given profile weight
+func rejection(weight, defaultWeight float64) float64 {
+	if weight > 0 {
+		return 1.0 / weight
+	}
+	return 1.0 / defaultWeight
+}
+
+// movementRe matches the roman or arabic numbering of a movement
+var movementRe = re.MustCompile(`^\s*([ivxlc]+|[0-9]+)\.\s+`)
+
+// splitMovement separates a title into its work and
+// movement, which are usually split by the last colon
+func splitMovement(title string) (string, string) {
+
+	if j := strings.LastIndex(title, ": "); j >= 0 {
+		return strings.TrimSpace(title[:j]), strings.TrimSpace(title[j+2:])
+	}
+	return strings.TrimSpace(title), ""
+
+}
+
+// movementNumber parses the numbering at the start of a
+// movement name, returning the name without it
+func movementNumber(movement string) (int, string) {
+
+	groups := movementRe.FindStringSubmatch(strings.ToLower(movement))
+	if len(groups) == 0 {
+		return 0, movement
+	}
+	rest := movement[len(groups[0]):]
+	if n, err := strconv.Atoi(groups[1]); nil == err {
+		return n, rest
+	}
+	return romanNumber(groups[1]), rest
+
+}
+
+func romanNumber(s string) int {
+
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100}
+	total := 0
+	for j := 0; j < len(s); j++ {
+		v := values[s[j]]
+		if j+1 < len(s) && values[s[j+1]] > v {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+
+}
+
+// title is the full title of the goal as work and movement
+func (ci *ClassicalInfo) title(goal *itunes.Track) string {
+
+	if ci.Work == "" || strings.Contains(strings.ToLower(goal.Name), strings.ToLower(ci.Work)) {
+		return goal.Name
+	}
+	movement := goal.Name
+	if ci.MovementName != "" {
+		movement = ci.MovementName
+	}
+	return ci.Work + ": " + movement
+
+}
+
+// titleCompare compares the work and movement of the two titles
+// separately, where catalogue numbers decide the work when available
+func (ci *ClassicalInfo) titleCompare(goal *itunes.Track, test *spotify.FullTrack, transliterateNames bool) (float64, string, string) {
+
+	goalTitle := ci.title(goal)
+	penalty, rule := versionMismatch(compareName(goalTitle, transliterateNames), compareName(test.Name, transliterateNames))
+
+	goalWork, goalMovement := splitMovement(goalTitle)
+	testWork, testMovement := splitMovement(test.Name)
+
+	var workScore float64
+	tier := "classical"
+	goalCatalogue := catalogueNumbers(goalWork)
+	testCatalogue := catalogueNumbers(testWork)
+	if len(goalCatalogue) > 0 && len(testCatalogue) > 0 {
+		if !sameCatalogue(goalCatalogue, testCatalogue) {
+			return titleRejection(), "rule", "catalogue"
+		}
+		tier = "catalogue"
+	} else {
+		workScore, _ = sCompareScore(compareName(goalWork, transliterateNames), compareName(testWork, transliterateNames))
+	}
+
+	goalNumber, goalName := movementNumber(goalMovement)
+	testNumber, testName := movementNumber(testMovement)
+	if ci.MovementNumber > 0 {
+		goalNumber = ci.MovementNumber
+	}
+	if goalNumber > 0 && testNumber > 0 && goalNumber != testNumber {
+		return titleRejection(), "rule", "movement"
+	}
+
+	// a missing movement could be the whole work, or could not
+	var movementScore float64
+	switch {
+	case goalName == "" && testName == "":
+		movementScore = 0
+	case goalName == "" || testName == "":
+		movementScore = 0.5
+	default:
+		movementScore, _ = sCompareScore(compareName(goalName, transliterateNames), compareName(testName, transliterateNames))
+	}
+
+	score := (workScore + movementScore) / 2
+	if penalty > 0 {
+		return score + penalty, "rule", rule
+	}
+	return score, tier, ""
+
+}
+
+// artistCompare compares the composer, performers and conductor
+// separately, each against the closest of the spotify artists
+func (ci *ClassicalInfo) artistCompare(goal *itunes.Track, test *spotify.FullTrack, transliterateNames bool) (float64, string, string) {
+
+	closest := func(name string) float64 {
+		best := 1.0
+		for _, a := range test.Artists {
+			if score, _, _ := artistCompare(name, a.Name, transliterateNames); score < best {
+				best = score
+			}
+		}
+		return best
+	}
+
+	var parts []float64
+	parts = append(parts, closest(ci.Composer))
+	if ci.Conductor != "" {
+		parts = append(parts, closest(ci.Conductor))
+	}
+
+	var performers []float64
+	for _, name := range artistSplit.Split(goal.Artist, -1) {
+		name = strings.TrimSpace(name)
+		if name == "" ||
+			normalizeName(name) == normalizeName(ci.Composer) ||
+			normalizeName(name) == normalizeName(ci.Conductor) {
+			continue
+		}
+		performers = append(performers, closest(name))
+	}
+	if len(performers) > 0 {
+		total := 0.0
+		for _, p := range performers {
+			total += p
+		}
+		parts = append(parts, total/float64(len(performers)))
+	}
+
+	total := 0.0
+	for _, p := range parts {
+		total += p
+	}
+	return total / float64(len(parts)), "classical", ""
+
+}
+
+// ClassicalSearchAttempts returns the queries to try before the usual
+// ones for a classical track, by composer, catalogue number and work
+func ClassicalSearchAttempts(goal *itunes.Track, ci *ClassicalInfo) []SearchQuery {
+
+	work, movement := splitMovement(ci.title(goal))
+	_, movement = movementNumber(movement)
+	composer := strings.ToLower(normalizeText(ci.Composer))
+	performer := strings.ToLower(normalizeText(strings.TrimSpace(artistSplit.Split(goal.Artist, -1)[0])))
+	work = strings.ToLower(normalizeText(work))
+	movement = strings.ToLower(normalizeText(movement))
+
+	var queries []SearchQuery
+	add := func(strategy, query string) {
+		// an empty movement leaves an empty quoted term
+		query = strings.Replace(query, ` ""`, "", -1)
+		queries = append(queries, SearchQuery{Strategy: strategy, Query: query, Pages: 2})
+	}
+	for _, c := range catalogueNumbers(work) {
+		add("classical performer catalogue", fmt.Sprintf(`"%s" "%s" "%s"`, performer, c.Text, movement))
+		add("classical composer catalogue", fmt.Sprintf(`"%s" "%s"`, composer, c.Text))
+	}
+	add("classical performer work", fmt.Sprintf(`"%s" "%s" "%s"`, performer, work, movement))
+	add("classical composer work", fmt.Sprintf(`"%s" "%s"`, composer, work))
+
+	return uniqueQueries(queries)
+
+}
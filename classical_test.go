@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestSameCatalogue(t *testing.T) {
+
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"Piano Sonata Op. 27 No. 2", "Sonata, Op. 27/2", true},
+		{"Piano Sonata Op. 27", "Sonata Op. 27 No. 2", true},
+		{"Piano Sonata Op. 27 No. 1", "Sonata Op. 27 No. 2", false},
+		{"Cello Suite BWV 1007", "Suite (BWV 1008)", false},
+		{"Eine kleine Nachtmusik, KV 525", "Serenade K. 525", true},
+	}
+	for _, test := range tests {
+		actual := sameCatalogue(catalogueNumbers(test.a), catalogueNumbers(test.b))
+		if actual != test.expected {
+			t.Errorf("expected %s and %s to be the same work: %v", test.a, test.b, test.expected)
+		}
+	}
+
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// csvFields are the track fields that can be mapped from csv columns
+// along with the header names that are recognized for each by default
+var csvFields = []struct {
+	Name    string
+	Headers []string
+}{
+	{"title", []string{"title", "name", "track", "track name", "song", "song name"}},
+	{"artist", []string{"artist", "artists", "artist name", "artist name(s)", "creator"}},
+	{"album", []string{"album", "album name", "album title", "release"}},
+	{"duration", []string{"duration", "length", "time", "duration (ms)", "track duration (ms)"}},
+	{"isrc", []string{"isrc"}},
+	{"playlist", []string{"playlist", "playlist name", "list"}},
+	{"position", []string{"position", "#", "index", "track position", "no", "no."}},
+}
+
+// csvReader reads a generic track list from a csv or tsv file,
+// with a column mapping that is guessed from the header row and
+// then confirmed or changed by the user
+type csvReader struct {
+	// column index of each field, guessed when not asked
+	columns map[string]int
+}
+
+func (r *csvReader) Detect(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".tsv", ".tab":
+		return true
+	}
+	return false
+}
+
+func (r *csvReader) askOptions(program *SimpleCommandProgram, path string) error {
+
+	records, err := readCSVRecords(path)
+	if nil != err {
+		return err
+	}
+	r.columns = askColumnMapping(program, records[0])
+	return nil
+
+}
+
+func (r *csvReader) Read(path string) (*Library, error) {
+
+	records, err := readCSVRecords(path)
+	if nil != err {
+		return nil, err
+	}
+
+	header := records[0]
+	columns := r.columns
+	if nil == columns {
+		columns = guessColumns(header)
+	}
+	if columns["title"] < 0 {
+		return nil, fmt.Errorf("a title column is required")
+	}
+
+	durationInMs := columns["duration"] >= 0 &&
+		strings.Contains(strings.ToLower(header[columns["duration"]]), "ms")
+
+	value := func(record []string, field string) string {
+		if col := columns[field]; col >= 0 && col < len(record) {
+			return strings.TrimSpace(record[col])
+		}
+		return ""
+	}
+
+	builder := newLibraryBuilder(path, FormatCSV)
+	defaultName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var names []string
+	byPlaylist := make(map[string][]*csvRow)
+	for _, record := range records[1:] {
+
+		track := &itunes.Track{
+			Name:      value(record, "title"),
+			Artist:    value(record, "artist"),
+			Album:     value(record, "album"),
+			TotalTime: parseDuration(value(record, "duration"), durationInMs),
+		}
+		if track.Name == "" {
+			continue
+		}
+
+		var extras *TrackExtras
+		if isrc := value(record, "isrc"); isrc != "" {
+			extras = &TrackExtras{ISRC: strings.ToUpper(isrc)}
+		}
+
+		name := value(record, "playlist")
+		if name == "" {
+			name = defaultName
+		}
+		if _, ok := byPlaylist[name]; !ok {
+			names = append(names, name)
+		}
+		position, _ := strconv.Atoi(value(record, "position"))
+		byPlaylist[name] = append(byPlaylist[name], &csvRow{
+			track:    builder.AddTrack(track, extras),
+			position: position,
+		})
+
+	}
+
+	for _, name := range names {
+		rows := byPlaylist[name]
+		sort.Stable(byPosition(rows))
+		tracks := make([]*itunes.Track, len(rows))
+		for j, row := range rows {
+			tracks[j] = row.track
+		}
+		builder.AddPlaylist(name, tracks)
+	}
+
+	return builder.lib, nil
+
+}
+
+// readCSVRecords reads all records of a csv or tsv file, the
+// first of which is the header row
+func readCSVRecords(path string) ([][]string, error) {
+
+	f, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	buffered := bufio.NewReader(f)
+	firstLine, err := buffered.Peek(4096)
+	if nil != err && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = csvDelimiter(path, string(firstLine))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if nil != err {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("no tracks found in %s", path)
+	}
+	records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	return records, nil
+
+}
+
+// guessColumns finds the column of each field by the recognized
+// header names, returning -1 for fields with no such column
+func guessColumns(header []string) map[string]int {
+
+	columns := make(map[string]int)
+	for _, field := range csvFields {
+		columns[field.Name] = -1
+		for col, name := range header {
+			if StringInSlice(strings.ToLower(strings.TrimSpace(name)), field.Headers) {
+				columns[field.Name] = col
+				break
+			}
+		}
+	}
+	return columns
+
+}
+
+// askColumnMapping guesses which column holds each field and lets
+// the user correct the guess, returning the column index of each
+// field, or -1 for fields that are not available
+func askColumnMapping(program *SimpleCommandProgram, header []string) map[string]int {
+
+	columns := guessColumns(header)
+
+	columnName := func(col int) string {
+		if col < 0 {
+			return "none"
+		}
+		return header[col]
+	}
+
+	program.Log("csv column mapping:")
+	for _, field := range csvFields {
+		program.Logf("  %-10s <- %s", field.Name, columnName(columns[field.Name]))
+	}
+	if program.AskYesNo("Use this column mapping?", true) {
+		return columns
+	}
+
+	for _, field := range csvFields {
+		for {
+			answer := program.AskStringDefault(
+				fmt.Sprintf("column for %s (name, number or none)", field.Name),
+				columnName(columns[field.Name]))
+			col, ok := csvColumn(header, answer)
+			if ok {
+				columns[field.Name] = col
+				break
+			}
+			program.Warningf("no column named %s", answer)
+		}
+	}
+
+	return columns
+
+}
+
+// csvColumn finds the column index for a header name or 1-based
+// column number, where "none" maps to -1
+func csvColumn(header []string, answer string) (int, bool) {
+
+	answer = strings.TrimSpace(answer)
+	if strings.ToLower(answer) == "none" || answer == "" {
+		return -1, true
+	}
+	for col, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), answer) {
+			return col, true
+		}
+	}
+	if n, err := strconv.Atoi(answer); nil == err && n > 0 && n <= len(header) {
+		return n - 1, true
+	}
+	return -1, false
+
+}
+
+// csvDelimiter picks the delimiter for a file, using the extension
+// for tsv files and the most common candidate in the header otherwise
+func csvDelimiter(path, head string) rune {
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return '\t'
+	}
+
+	line := strings.SplitN(head, "\n", 2)[0]
+	best, count := ',', strings.Count(line, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if n := strings.Count(line, string(candidate)); n > count {
+			best, count = candidate, n
+		}
+	}
+	return best
+
+}
+
+// parseDuration parses durations like "3:45", "1:02:03", "225" (seconds)
+// or "225000" (milliseconds) into milliseconds, returning 0 if invalid
+func parseDuration(s string, inMs bool) int {
+
+	if s == "" {
+		return 0
+	}
+
+	if strings.Contains(s, ":") {
+		total := 0
+		for _, part := range strings.Split(s, ":") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if nil != err {
+				return 0
+			}
+			total = total*60 + n
+		}
+		return total * 1000
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if nil != err {
+		return 0
+	}
+	// nothing is 3 hours long, so large values are milliseconds
+	if inMs || n > 10000 {
+		return int(n)
+	}
+	return int(n * 1000)
+
+}
+
+type csvRow struct {
+	track    *itunes.Track
+	position int
+}
+
+type byPosition []*csvRow
+
+func (s byPosition) Len() int           { return len(s) }
+func (s byPosition) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byPosition) Less(i, j int) bool { return s[i].position < s[j].position }
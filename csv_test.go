@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCSVDelimiter(t *testing.T) {
+
+	tests := []struct {
+		path     string
+		head     string
+		expected rune
+	}{
+		{"list.csv", "title,artist,album\na;b;c;d\n", ','},
+		{"list.csv", "title;artist;album\n", ';'},
+		{"list.csv", "title\tartist\talbum", '\t'},
+		{"list.csv", "title;artist,album\n", ','}, // ties keep the comma
+		{"list.csv", "title\n", ','},
+		{"list.csv", "", ','},
+		{"list.TSV", "title,artist,album\n", '\t'},
+		{"list.tab", "title;artist\n", '\t'},
+	}
+
+	for _, test := range tests {
+		if d := csvDelimiter(test.path, test.head); d != test.expected {
+			t.Errorf("expected %q for %s %q, got %q", test.expected, test.path, test.head, d)
+		}
+	}
+
+}
+
+func TestGuessColumns(t *testing.T) {
+
+	tests := []struct {
+		header   []string
+		expected map[string]int
+	}{
+		{
+			[]string{"Track Name", " Artist Name(s) ", "Album Name", "Track Duration (ms)", "ISRC"},
+			map[string]int{"title": 0, "artist": 1, "album": 2, "duration": 3, "isrc": 4, "playlist": -1, "position": -1},
+		},
+		{
+			// the first matching column is used for each field
+			[]string{"#", "Playlist", "Song", "Title", "Creator", "Time"},
+			map[string]int{"title": 2, "artist": 4, "album": -1, "duration": 5, "isrc": -1, "playlist": 1, "position": 0},
+		},
+		{
+			[]string{"something", "else"},
+			map[string]int{"title": -1, "artist": -1, "album": -1, "duration": -1, "isrc": -1, "playlist": -1, "position": -1},
+		},
+	}
+
+	for _, test := range tests {
+		columns := guessColumns(test.header)
+		for field, col := range test.expected {
+			if columns[field] != col {
+				t.Errorf("expected %s in column %d of %q, got %d", field, col, test.header, columns[field])
+			}
+		}
+	}
+
+}
+
+func TestCSVColumn(t *testing.T) {
+
+	header := []string{"Title", "Artist", "3"}
+	tests := []struct {
+		answer   string
+		col      int
+		expected bool
+	}{
+		{"artist", 1, true},
+		{" Title ", 0, true},
+		{"2", 1, true},
+		{"3", 2, true}, // header names win over numbers
+		{"none", -1, true},
+		{"", -1, true},
+		{"4", -1, false},
+		{"0", -1, false},
+		{"album", -1, false},
+	}
+
+	for _, test := range tests {
+		col, ok := csvColumn(header, test.answer)
+		if col != test.col || ok != test.expected {
+			t.Errorf("expected %d, %v for %q, got %d, %v", test.col, test.expected, test.answer, col, ok)
+		}
+	}
+
+}
+
+func TestParseDuration(t *testing.T) {
+
+	tests := []struct {
+		value    string
+		inMs     bool
+		expected int
+	}{
+		{"3:45", false, 225000},
+		{"1:02:03", false, 3723000},
+		{" 3 : 05 ", false, 185000},
+		{"3:4x", false, 0},
+		{"225", false, 225000},
+		{"225.5", false, 225500},
+		{"10000", false, 10000000},
+		{"10001", false, 10001},
+		{"225000", false, 225000},
+		{"225", true, 225},
+		{"225000.9", true, 225000},
+		{"", false, 0},
+		{"abc", false, 0},
+	}
+
+	for _, test := range tests {
+		if d := parseDuration(test.value, test.inMs); d != test.expected {
+			t.Errorf("expected %d for %q (ms: %v), got %d", test.expected, test.value, test.inMs, d)
+		}
+	}
+
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// Explainer shows how a single library track is matched, with the
+// score breakdown of its cached match and of its search candidates
+type Explainer struct {
+	mapper  *Importer
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// NewExplainer creates a new explainer for the command program and library
+func NewExplainer(program *SimpleCommandProgram, lib *Library) *Explainer {
+
+	mapper := &Importer{
+		matchCache: InitMatchCache(lib.LibraryFile),
+		lib:        lib,
+		program:    program,
+	}
+	mapper.askMatchSettings(program)
+
+	return &Explainer{
+		mapper:  mapper,
+		lib:     lib,
+		program: program,
+	}
+
+}
+
+// Run explains the track named on the command line, or asks for one
+func (e *Explainer) Run() {
+
+	// the command may have been chosen from the menu, with no arguments
+	var name string
+	if len(os.Args) > 2 {
+		name = strings.Join(os.Args[2:], " ")
+	}
+	if name == "" {
+		name = e.program.AskString("name of the track to explain")
+	}
+
+	track := e.findTrack(name)
+	if nil == track {
+		e.program.Errorf("no track in the library is named %s", name)
+		return
+	}
+
+	goal := PreprocessTrackArtists(track)
+	e.program.Logf("library track: %s", ItunesCacheString(track))
+	if goal.Name != track.Name || goal.Artist != track.Artist {
+		e.program.Logf("  searched as: %s", ItunesCacheString(goal))
+	}
+
+	if cached, ok := e.mapper.matchCache.TrackMap[track.PersistentID]; ok {
+		e.program.Logf("cached match: %s [%1.4f]", cached.SpotifyTrack, cached.Score)
+		if nil != cached.Breakdown {
+			e.program.Logf("  %s", cached.Breakdown)
+		} else if mt := e.mapper.matchCache.TrackMap.GetMatch(track); nil != mt && mt.Valid() {
+			e.program.Logf("  %s (rescored)", e.mapper.compareTrack(goal, mt.spotify, false))
+		}
+	} else {
+		e.program.Log("cached match: none, this track has not been matched yet")
+	}
+
+	queries := SearchAttempts(goal)
+	if len(queries) > 3 {
+		queries = queries[:3]
+	}
+
+	var results []spotify.FullTrack
+	seen := make(map[spotify.ID]bool)
+	for _, query := range queries {
+		e.program.Logf("searching %s (%s)...", query.Query, query.Strategy)
+		for _, res := range Session.SearchTracks(query.Query, 1) {
+			if !seen[res.ID] {
+				results = append(results, res)
+				seen[res.ID] = true
+			}
+		}
+	}
+
+	scored := e.mapper.scoreTracks(results, goal)
+	sort.Sort(byScoreAndDate(scored))
+	if len(scored) > 10 {
+		scored = scored[:10]
+	}
+
+	e.program.Logf("best candidates (matched at %1.4f or less):", thresholdMatched)
+	for _, mt := range scored {
+		e.program.Logf("  @%1.4f  %s", mt.score, SpotifyCacheString(mt.spotify))
+		e.program.Logf("      %s", mt.breakdown)
+	}
+
+}
+
+// findTrack finds the library track with the given name, asking
+// which one was meant if there are several
+func (e *Explainer) findTrack(name string) *itunes.Track {
+
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	var exact, partial []*itunes.Track
+	for _, track := range e.lib.Tracks {
+		trackName := strings.ToLower(track.Name)
+		if trackName == name {
+			exact = append(exact, track)
+		} else if strings.Contains(trackName, name) {
+			partial = append(partial, track)
+		}
+	}
+
+	found := append(exact, partial...)
+	switch len(found) {
+	case 0:
+		return nil
+	case 1:
+		return found[0]
+	}
+	if len(found) > 20 {
+		found = found[:20]
+	}
+
+	var options []string
+	for _, track := range found {
+		options = append(options, ItunesCacheString(track))
+	}
+	return found[e.program.AskOption(fmt.Sprintf("which %s did you mean", name), options)]
+
+}
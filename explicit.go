@@ -0,0 +1,46 @@
+package main
+
+// ExplicitPreference decides whether explicit or clean
+// versions of tracks are preferred when matching
+type ExplicitPreference string
+
+// all available explicit preferences
+const (
+	ExplicitMatchSource    ExplicitPreference = "source"
+	ExplicitPreferExplicit ExplicitPreference = "explicit"
+	ExplicitPreferClean    ExplicitPreference = "clean"
+)
+
+// explicitPenalty is added to tracks that are not the
+// wanted explicit or clean version
+const explicitPenalty = 0.05
+
+// wantExplicit returns whether an explicit version is wanted for a
+// track with the given extras, and false for ok if either will do
+func (ep ExplicitPreference) wantExplicit(extras *TrackExtras) (want, ok bool) {
+
+	switch ep {
+	case ExplicitPreferExplicit:
+		return true, true
+	case ExplicitPreferClean:
+		return false, true
+	}
+
+	// a track without a content rating could be either
+	if extras.Explicit {
+		return true, true
+	}
+	if extras.Clean {
+		return false, true
+	}
+	return false, false
+
+}
+
+// explicitString names the version of a track
+func explicitString(explicit bool) string {
+	if explicit {
+		return "explicit"
+	}
+	return "clean"
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+func TestWantExplicit(t *testing.T) {
+
+	explicit := &TrackExtras{Explicit: true}
+	clean := &TrackExtras{Clean: true}
+	unrated := &TrackExtras{}
+
+	tests := []struct {
+		pref     ExplicitPreference
+		extras   *TrackExtras
+		want, ok bool
+	}{
+		{ExplicitMatchSource, explicit, true, true},
+		{ExplicitMatchSource, clean, false, true},
+		{ExplicitMatchSource, unrated, false, false},
+		{ExplicitPreferExplicit, clean, true, true},
+		{ExplicitPreferExplicit, unrated, true, true},
+		{ExplicitPreferClean, explicit, false, true},
+		{ExplicitPreferClean, unrated, false, true},
+	}
+
+	for _, test := range tests {
+		want, ok := test.pref.wantExplicit(test.extras)
+		if want != test.want || ok != test.ok {
+			t.Errorf("expected %s with %+v to want %v (%v), got %v (%v)",
+				test.pref, *test.extras, test.want, test.ok, want, ok)
+		}
+	}
+
+}
+
+func TestExplicitPenalty(t *testing.T) {
+
+	goal := &itunes.Track{Name: "first song", Artist: "the band", Album: "first album"}
+	test := &spotify.FullTrack{}
+	test.Name = "first song"
+	test.Artists = []spotify.SimpleArtist{{Name: "the band"}}
+	test.Album.Name = "first album"
+	test.Explicit = true
+
+	either := CompareTracks(goal, test, CompareOptions{})
+	if either.ExplicitMismatch {
+		t.Errorf("expected no mismatch without a wanted version, got %s", either)
+	}
+
+	want := true
+	wanted := CompareTracks(goal, test, CompareOptions{Explicit: &want})
+	if wanted.ExplicitMismatch || wanted.Total() != either.Total() {
+		t.Errorf("expected the wanted version not to be penalized, got %s", wanted)
+	}
+
+	want = false
+	unwanted := CompareTracks(goal, test, CompareOptions{Explicit: &want})
+	if !unwanted.ExplicitMismatch {
+		t.Fatalf("expected an explicit track to mismatch a clean one, got %s", unwanted)
+	}
+	if math.Abs(unwanted.Total()-either.Total()-explicitPenalty) > 1e-9 {
+		t.Errorf("expected the mismatch to add %f, got %f", explicitPenalty, unwanted.Total()-either.Total())
+	}
+
+	// the wanted version is preferred even over an isrc match
+	unwanted.ISRC = true
+	if math.Abs(unwanted.Total()-unwanted.Rank-explicitPenalty) > 1e-9 {
+		t.Errorf("expected the penalty to apply to an isrc match, got %f", unwanted.Total())
+	}
+
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// all available export formats
+const (
+	ExportText = "txt"
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// Exporter matches library playlists against spotify and writes the
+// results as files of spotify uris rather than changing the account
+type Exporter struct {
+
+	// export settings
+	Format         string
+	IncludeLibrary bool
+	OutputDir      string
+
+	// runtime
+	mapper  *Importer
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// ExportedTrack is one library track and what it was matched to
+type ExportedTrack struct {
+	Position     int     `json:"position"`
+	Name         string  `json:"name"`
+	Artist       string  `json:"artist"`
+	Album        string  `json:"album"`
+	URI          string  `json:"uri,omitempty"`
+	SpotifyTrack string  `json:"spotifyTrack,omitempty"`
+	Score        float64 `json:"score"`
+}
+
+// NewExporter creates a new exporter for the command program and
+// library that are supplied
+func NewExporter(program *SimpleCommandProgram, lib *Library) *Exporter {
+
+	formats := []string{ExportText, ExportCSV, ExportJSON}
+	format := formats[program.AskOptionDefault("export format", []string{
+		"txt: spotify uris only, to paste into the spotify app",
+		"csv: uris with the matched track and score",
+		"json: uris with the matched track and score",
+	}, 0)]
+
+	return &Exporter{
+		Format:         format,
+		IncludeLibrary: program.AskYesNo("Export the whole library as a playlist?", false),
+		OutputDir: program.AskStringDefault(
+			"export to folder", itspFile(lib.LibraryFile, "export")),
+
+		mapper:  newTrackMapper(program, lib),
+		lib:     lib,
+		program: program,
+	}
+
+}
+
+// Run this exporter with the current configuration
+func (e *Exporter) Run() {
+
+	defer e.mapper.missingLog.SaveLog()
+
+	if err := os.MkdirAll(e.OutputDir, 0755); nil != err {
+		e.program.Errorf("Error creating export folder: %s", err)
+		return
+	}
+
+	count := 0
+	if e.IncludeLibrary {
+		e.program.Log("exporting itunes library...")
+		e.exportPlaylist("iTunes Library", e.lib.Tracks)
+		count++
+	}
+
+	for _, iList := range e.lib.Playlists {
+		if e.mapper.shouldSkipPlaylist(iList) {
+			continue
+		}
+		e.program.Logf("exporting %s...", iList.Name)
+		e.exportPlaylist(iList.Name, iList.PlaylistItems)
+		count++
+	}
+
+	e.program.Logf("exported %d playlists to %s", count, e.OutputDir)
+
+}
+
+func (e *Exporter) exportPlaylist(name string, tracks []*itunes.Track) {
+
+	var exported []*ExportedTrack
+	e.mapper.matchNum = 0
+	e.mapper.matchTotal = len(tracks)
+	for _, track := range tracks {
+
+		if e.mapper.shouldSkipTrack(track) {
+			e.mapper.matchTotal--
+			continue
+		}
+
+		et := &ExportedTrack{
+			Position: len(exported) + 1,
+			Name:     track.Name,
+			Artist:   track.Artist,
+			Album:    track.Album,
+			Score:    -1,
+		}
+		if id := e.mapper.mappedTrackID(track); id != "" {
+			et.URI = "spotify:track:" + string(id)
+		} else {
+			e.mapper.missingLog.Log(name, track)
+		}
+		if cached, ok := e.mapper.matchCache.TrackMap[track.PersistentID]; ok && et.URI != "" {
+			et.SpotifyTrack = cached.SpotifyTrack
+			et.Score = cached.Score
+		}
+		exported = append(exported, et)
+
+	}
+
+	fileName := filepath.Join(e.OutputDir, safeFileName(name)+"."+e.Format)
+	if err := e.write(fileName, name, exported); nil != err {
+		e.program.Errorf("Error writing %s: %s", fileName, err)
+	}
+
+}
+
+func (e *Exporter) write(fileName, name string, tracks []*ExportedTrack) error {
+
+	f, err := os.Create(fileName)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	switch e.Format {
+
+	case ExportCSV:
+		w := csv.NewWriter(f)
+		w.Write([]string{"position", "name", "artist", "album", "uri", "spotify track", "score"})
+		for _, t := range tracks {
+			w.Write([]string{
+				strconv.Itoa(t.Position), t.Name, t.Artist, t.Album,
+				t.URI, t.SpotifyTrack, strconv.FormatFloat(t.Score, 'f', 4, 64),
+			})
+		}
+		w.Flush()
+		return w.Error()
+
+	case ExportJSON:
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Name   string           `json:"name"`
+			Tracks []*ExportedTrack `json:"tracks"`
+		}{name, tracks})
+
+	default:
+		// unmatched tracks are left out, since the spotify
+		// app rejects the whole paste if any line is invalid
+		for _, t := range tracks {
+			if t.URI == "" {
+				continue
+			}
+			if _, err = fmt.Fprintln(f, t.URI); nil != err {
+				return err
+			}
+		}
+		return nil
+
+	}
+
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExporterWrite(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tracks := []*ExportedTrack{
+		{Position: 1, Name: "First Song", Artist: "The Band", Album: "First Album",
+			URI: "spotify:track:first", SpotifyTrack: "First Song - The Band", Score: 0.125},
+		{Position: 2, Name: "Missing Song", Artist: "The Band", Album: "First Album", Score: -1},
+		{Position: 3, Name: "Third Song", Artist: "The Band", Album: "First Album",
+			URI: "spotify:track:third", SpotifyTrack: "Third Song - The Band", Score: 0.5},
+	}
+
+	write := func(format string) string {
+		e := &Exporter{Format: format}
+		fileName := filepath.Join(dir, "playlist."+format)
+		if err := e.write(fileName, "My Playlist", tracks); nil != err {
+			t.Fatalf("expected %s to be written: %s", format, err)
+		}
+		return fileName
+	}
+
+	data, err := ioutil.ReadFile(write(ExportText))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if expected := "spotify:track:first\nspotify:track:third\n"; string(data) != expected {
+		t.Errorf("expected only the matched uris in txt, got %q", data)
+	}
+
+	f, err := os.Open(write(ExportCSV))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[0][0] != "position" {
+		t.Fatalf("expected a header and every track in csv, got %v", records)
+	}
+	if records[2][1] != "Missing Song" || records[2][4] != "" || records[2][6] != "-1.0000" {
+		t.Errorf("expected the unmatched track without a uri, got %v", records[2])
+	}
+	if records[3][4] != "spotify:track:third" || records[3][6] != "0.5000" {
+		t.Errorf("expected the matched track with its uri and score, got %v", records[3])
+	}
+
+	data, err = ioutil.ReadFile(write(ExportJSON))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var exported struct {
+		Name   string
+		Tracks []*ExportedTrack
+	}
+	if err = json.Unmarshal(data, &exported); nil != err {
+		t.Fatal(err)
+	}
+	if exported.Name != "My Playlist" || len(exported.Tracks) != 3 {
+		t.Fatalf("expected the playlist with every track in json, got %+v", exported)
+	}
+	if *exported.Tracks[0] != *tracks[0] || *exported.Tracks[1] != *tracks[1] {
+		t.Errorf("expected the tracks to be written as they are, got %+v", exported.Tracks)
+	}
+
+}
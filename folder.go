@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// folderReader builds a library by scanning a folder for audio files
+// and reading their embedded tags, optionally creating a playlist for
+// each folder that contains music
+type folderReader struct {
+	program *SimpleCommandProgram
+
+	// create a playlist for each folder
+	folderPlaylists bool
+}
+
+func (r *folderReader) Detect(path string) bool {
+	stat, err := os.Stat(path)
+	return nil == err && stat.IsDir()
+}
+
+func (r *folderReader) askOptions(program *SimpleCommandProgram, path string) error {
+	r.folderPlaylists = program.AskYesNo("Create a playlist for each folder?", false)
+	return nil
+}
+
+func (r *folderReader) Read(path string) (*Library, error) {
+
+	builder := newLibraryBuilder(path, FormatFolder)
+	byFolder := make(map[string][]*itunes.Track)
+
+	r.program.Logf("scanning %s for music...", path)
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+
+		if nil != err {
+			r.program.Warningf("cannot read %s: %s", file, err)
+			return nil
+		}
+		if info.IsDir() || !StringInSlice(strings.ToLower(filepath.Ext(file)), audioExtensions) {
+			return nil
+		}
+
+		track := &itunes.Track{
+			Location: file,
+			Kind:     strings.ToUpper(strings.TrimPrefix(filepath.Ext(file), ".")) + " audio file",
+			Size:     int(info.Size()),
+		}
+
+		var extras *TrackExtras
+		tags, err := ReadFileTags(file)
+		if nil != err {
+			r.program.Warningf("cannot read tags from %s: %s", file, err)
+		} else {
+			tags.Apply(track)
+			extras = tags.Extras()
+		}
+
+		// untagged files are commonly named "Artist - Title"
+		// and kept in a folder named for the album
+		if track.Name == "" {
+			base := filepath.Base(file)
+			track.Name, track.Artist = splitArtistTitle(
+				strings.TrimSuffix(base, filepath.Ext(base)), track.Artist)
+		}
+		if track.Album == "" {
+			track.Album = filepath.Base(filepath.Dir(file))
+		}
+
+		track = builder.AddTrack(track, extras)
+		folder := filepath.Dir(file)
+		byFolder[folder] = append(byFolder[folder], track)
+
+		if len(builder.lib.Tracks)%500 == 0 {
+			r.program.Logf("read %d tracks...", len(builder.lib.Tracks))
+		}
+		return nil
+
+	})
+	if nil != err {
+		return nil, err
+	}
+
+	r.program.Logf("found %d tracks in %d folders", len(builder.lib.Tracks), len(byFolder))
+
+	if r.folderPlaylists {
+
+		var folders []string
+		for folder := range byFolder {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+
+		for _, folder := range folders {
+			tracks := byFolder[folder]
+			sort.Stable(byDiscAndTrack(tracks))
+			name, err := filepath.Rel(path, folder)
+			if nil != err || name == "." {
+				name = filepath.Base(folder)
+			}
+			builder.AddPlaylist(filepath.ToSlash(name), tracks)
+		}
+
+	}
+
+	return builder.lib, nil
+
+}
+
+type byDiscAndTrack []*itunes.Track
+
+func (s byDiscAndTrack) Len() int      { return len(s) }
+func (s byDiscAndTrack) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDiscAndTrack) Less(i, j int) bool {
+	if s[i].DiscNumber != s[j].DiscNumber {
+		return s[i].DiscNumber < s[j].DiscNumber
+	}
+	return s[i].TrackNumber < s[j].TrackNumber
+}
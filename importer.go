@@ -18,58 +18,125 @@ type Importer struct {
 	ImportPlaylists   bool
 	GroupPlaylists    bool
 	ImportDisabled    bool
+	SkipStreaming     bool
+	AlbumFirst        bool
 	PlaylistGroup     string
 
 	// match settings
 	PreferOriginal bool
 	GuessMatching  bool
+	ReviewScreen   bool
+	DeferReview    bool
+	Transliterate  bool
+	Classical      bool
+	Explicit       ExplicitPreference
+
+	// skip, force and absent artist rules
+	rules *MatchRules
 
 	// match processing
 	matchNum   int
 	matchTotal int
+	queryStats QueryStats
 
 	// cache
 	missingLog *MissingLog
 	matchCache *MatchCache
+	review     *ReviewQueue
+	decisions  *DecisionLog
 	trackCache map[int]*MatchedTrack
-	albumCache map[string][]spotify.FullTrack
+	albumCache map[string][]spotify.FullTrack // by albumKey
+
+	// albums chosen during review for all of their tracks
+	forcedAlbums map[string][]spotify.FullTrack
 
 	// runtime
-	lib     *itunes.Library
+	lib     *Library
 	program *SimpleCommandProgram
 }
 
 // NewImporter creates a new importer for the command program and
 // itunes library that are supplied
-func NewImporter(program *SimpleCommandProgram, lib *itunes.Library) *Importer {
+func NewImporter(program *SimpleCommandProgram, lib *Library) *Importer {
 
-	i := &Importer{
-		AddToLibrary:    program.AskYesNo("Add all tracks to your library?", false),
-		ImportPlaylists: program.AskYesNo("Import playlists?", true),
-		GroupPlaylists:  false, //program.AskYesNo("Group all itunes playlists?", true),
-		PlaylistGroup:   "iTunes Playlists",
-		PreferOriginal:  program.AskYesNo("Prefer non-consolidation albums?", true),
-		GuessMatching:   program.AskYesNo("Guess when there are mutliple excellent matches?", true),
-		ImportDisabled:  program.AskYesNo("Import unchecked songs?", false),
+	addToLibrary := program.AskYesNo("Add all tracks to your library?", false)
+	importPlaylists := program.AskYesNo("Import playlists?", true)
 
-		matchTotal: len(lib.Tracks),
+	i := newTrackMapper(program, lib)
+	i.DeferReview = program.AskYesNo("Queue uncertain matches to review later instead of asking now?", false)
+	i.AlbumFirst = program.AskYesNo("Match whole albums before searching for single tracks?", true)
+	i.AddToLibrary = addToLibrary
+	i.ImportPlaylists = importPlaylists
+	i.GroupPlaylists = false //program.AskYesNo("Group all itunes playlists?", true)
+	i.PlaylistGroup = "iTunes Playlists"
+
+	return i
+
+}
+
+// newTrackMapper creates an importer that is only used to map tracks
+// to spotify, asking for the match settings but none of the import ones
+func newTrackMapper(program *SimpleCommandProgram, lib *Library) *Importer {
 
-		missingLog: InitMissingLog(lib.LibraryFile),
-		matchCache: InitMatchCache(lib.LibraryFile),
-		trackCache: make(map[int]*MatchedTrack),
-		albumCache: make(map[string][]spotify.FullTrack),
-		lib:        lib,
-		program:    program,
+	rules, err := LoadMatchRules(lib.LibraryFile)
+	if nil != err {
+		program.Warningf("ignoring rules: %s", err)
 	}
 
+	i := &Importer{
+		GuessMatching:  program.AskYesNo("Guess when there are mutliple excellent matches?", true),
+		ReviewScreen:   program.AskYesNo("Review uncertain matches in full screen?", false),
+		ImportDisabled: program.AskYesNo("Import unchecked songs?", false),
+		SkipStreaming: lib.Format == FormatMusic &&
+			program.AskYesNo("Skip Apple Music streaming-only songs?", false),
+
+		matchTotal: len(lib.Tracks),
+		queryStats: make(QueryStats),
+		rules:      rules,
+
+		missingLog:   InitMissingLog(lib.LibraryFile),
+		matchCache:   InitMatchCache(lib.LibraryFile),
+		review:       InitReviewQueue(lib.LibraryFile),
+		decisions:    InitDecisionLog(lib.LibraryFile),
+		trackCache:   make(map[int]*MatchedTrack),
+		albumCache:   make(map[string][]spotify.FullTrack),
+		forcedAlbums: make(map[string][]spotify.FullTrack),
+		lib:          lib,
+		program:      program,
+	}
+	i.askMatchSettings(program)
+	i.matchCache.MigrateAlbums(lib)
 	return i
 
 }
 
+// askMatchSettings asks for the settings that decide how tracks are
+// scored, so that everything that scores tracks does so the same way
+func (i *Importer) askMatchSettings(program *SimpleCommandProgram) {
+
+	preferences := []ExplicitPreference{ExplicitMatchSource, ExplicitPreferExplicit, ExplicitPreferClean}
+	i.Explicit = preferences[program.AskOptionDefault(
+		"when a song has both explicit and clean versions",
+		[]string{
+			"match the content rating in the library",
+			"prefer the explicit version",
+			"prefer the clean version",
+		}, 0)]
+	i.PreferOriginal = program.AskYesNo("Prefer original albums over compilations?", true)
+	i.Transliterate = program.AskYesNo("Transliterate cyrillic, greek and kana names when matching?", false)
+	i.Classical = program.AskYesNo("Match classical tracks by composer, work and movement?", false)
+
+}
+
 // Run this importer with the current configuration
 func (i *Importer) Run() {
 
 	defer i.missingLog.SaveLog()
+	defer i.review.SaveQueue()
+	defer i.logQueryStats()
+
+	// positions only hold for the playlists created by this run
+	i.review.ClearPlacements()
 
 	var user *spotify.PrivateUser
 	var err error
@@ -82,6 +149,11 @@ func (i *Importer) Run() {
 		return
 	}
 
+	if i.AlbumFirst {
+		i.program.Log("matching whole albums...")
+		i.matchAlbums(i.lib.Tracks)
+	}
+
 	if i.AddToLibrary {
 
 		i.program.Log("adding tracks to library...")
@@ -98,9 +170,10 @@ func (i *Importer) Run() {
 
 			mt := i.getMappedTrack(track.TrackID)
 			if mt == nil {
-				i.missingLog.Log("Spotify Library", track)
+				i.logMissing("Spotify Library", "", 0, track)
 			}
 			if mt != nil && mt.Valid() {
+				i.flagMatch("Spotify Library", mt)
 				chunk = append(chunk, mt.spotify.ID)
 			}
 			if len(chunk) == 100 {
@@ -147,6 +220,7 @@ func (i *Importer) Run() {
 	}
 
 	var chunk []spotify.ID
+	position := 0
 	i.matchNum = 0
 	i.matchTotal = len(i.lib.Tracks)
 	for _, track := range i.lib.Tracks {
@@ -158,10 +232,12 @@ func (i *Importer) Run() {
 
 		mt := i.getMappedTrack(track.TrackID)
 		if mt == nil {
-			i.missingLog.Log("iTunes Library", track)
+			i.logMissing("iTunes Library", libraryPlaylist.ID, position, track)
 		}
 		if mt != nil && mt.Valid() {
+			i.flagMatch("iTunes Library", mt)
 			chunk = append(chunk, mt.spotify.ID)
+			position++
 		}
 		if len(chunk) == 100 {
 			for {
@@ -198,14 +274,7 @@ func (i *Importer) Run() {
 
 		for _, iList := range i.lib.Playlists {
 
-			if iList.Master ||
-				iList.TVShows ||
-				iList.Movies ||
-				iList.ITunesU ||
-				iList.Audiobooks ||
-				iList.Books ||
-				iList.Folder ||
-				iList.Music {
+			if i.shouldSkipPlaylist(iList) {
 				continue
 			}
 
@@ -225,6 +294,7 @@ func (i *Importer) Run() {
 			}
 
 			var chunk []spotify.ID
+			position := 0
 			i.matchNum = 0
 			i.matchTotal = len(iList.PlaylistItems)
 			for _, track := range iList.PlaylistItems {
@@ -236,10 +306,12 @@ func (i *Importer) Run() {
 
 				mt := i.getMappedTrack(track.TrackID)
 				if mt == nil {
-					i.missingLog.Log(iList.Name, track)
+					i.logMissing(iList.Name, sList.ID, position, track)
 				}
 				if mt != nil && mt.Valid() {
+					i.flagMatch(iList.Name, mt)
 					chunk = append(chunk, mt.spotify.ID)
+					position++
 				}
 				if len(chunk) == 100 {
 					for {
@@ -276,6 +348,20 @@ func (i *Importer) Run() {
 
 }
 
+func (i *Importer) shouldSkipPlaylist(iList *itunes.Playlist) bool {
+
+	return iList.Master ||
+		iList.TVShows ||
+		iList.Movies ||
+		iList.ITunesU ||
+		iList.Audiobooks ||
+		iList.Books ||
+		iList.Folder ||
+		iList.Music ||
+		i.lib.DistinguishedKind(iList) != 0
+
+}
+
 func (i *Importer) shouldSkipTrack(track *itunes.Track) bool {
 
 	if track.Podcast || track.Movie || track.ITunesU || track.TVShow {
@@ -286,10 +372,56 @@ func (i *Importer) shouldSkipTrack(track *itunes.Track) bool {
 		return true
 	}
 
+	if i.SkipStreaming && i.lib.Extras(track).StreamingOnly() {
+		return true
+	}
+
+	if i.rules.ShouldSkip(track) {
+		return true
+	}
+
 	return false
 
 }
 
+// logMissing records a track that could not be added to the destination
+// playlist (or saved tracks when playlistID is empty), where tracks that
+// are queued for review remember the position to be inserted at later
+func (i *Importer) logMissing(destination string, playlistID spotify.ID, position int, track *itunes.Track) {
+
+	if i.DeferReview && i.review.Place(track.PersistentID, playlistID.String(), position) {
+		return
+	}
+	i.missingLog.Log(destination, track)
+
+}
+
+// flagMatch records a matched track that the search said cannot be
+// played in the user's market or that is not the wanted explicit or
+// clean version
+func (i *Importer) flagMatch(destination string, mt *MatchedTrack) {
+
+	if nil != mt.spotify.IsPlayable && !*mt.spotify.IsPlayable {
+		i.missingLog.Flag(destination, mt.itunes,
+			fmt.Sprintf("not playable in %s", Session.Market()))
+	}
+	if want := i.wantExplicit(mt.itunes); nil != want && *want != mt.spotify.Explicit {
+		i.missingLog.Flag(destination, mt.itunes,
+			fmt.Sprintf("matched the %s version", explicitString(mt.spotify.Explicit)))
+	}
+
+}
+
+// wantExplicit is whether the explicit or clean version
+// of the goal is wanted, or nil if either will do
+func (i *Importer) wantExplicit(goal *itunes.Track) *bool {
+	want, ok := i.Explicit.wantExplicit(i.lib.Extras(goal))
+	if !ok {
+		return nil
+	}
+	return &want
+}
+
 func (i *Importer) cacheTrack(mt *MatchedTrack) *MatchedTrack {
 
 	i.program.Logf("  @%1.4f  %s", mt.score, SpotifyCacheString(mt.spotify))
@@ -298,8 +430,14 @@ func (i *Importer) cacheTrack(mt *MatchedTrack) *MatchedTrack {
 	i.matchCache.TrackMap.Store(mt)
 
 	if mt.itunes.Album != "" && mt.Valid() {
-		i.albumCache[mt.itunes.Album] = albumTracks(mt.FullAlbum())
-		i.matchCache.AlbumMap.Store(mt)
+		key := i.albumKey(mt.itunes)
+		i.albumCache[key] = albumTracks(mt.FullAlbum())
+		i.matchCache.AlbumMap.Store(key, mt)
+
+		// a compilation is matched as a whole
+		if isCompilation(mt.itunes) && mt.spotify.Album.AlbumType == "compilation" {
+			i.alignCompilation(mt, i.albumCache[key])
+		}
 	}
 
 	i.matchCache.SaveCache()
@@ -308,6 +446,232 @@ func (i *Importer) cacheTrack(mt *MatchedTrack) *MatchedTrack {
 
 }
 
+// matchAlbums matches the tracks of each album in the given tracks all
+// at once, by finding the spotify album whose tracklist lines up best,
+// leaving the tracks that could not be aligned to be matched one by one
+func (i *Importer) matchAlbums(tracks []*itunes.Track) {
+
+	var pending []*itunes.Track
+	for _, track := range tracks {
+		if i.shouldSkipTrack(track) {
+			continue
+		}
+		if _, ok := i.trackCache[track.TrackID]; ok {
+			continue
+		}
+		if _, ok := i.matchCache.TrackMap[track.PersistentID]; ok {
+			continue
+		}
+		pending = append(pending, track)
+	}
+
+	albums := GroupAlbums(pending)
+	for n, album := range albums {
+		if len(album.Tracks) < minAlbumTracks {
+			continue
+		}
+		i.program.Logf("            \nalbum %04d/%04d: %s (%s)\n",
+			n+1, len(albums), album.Name, album.Artist)
+		i.matchAlbum(album)
+	}
+
+}
+
+// matchAlbum searches for the given album and caches the
+// tracks that align with the best scoring spotify album
+func (i *Importer) matchAlbum(album *LibraryAlbum) {
+
+	goals := make([]*itunes.Track, len(album.Tracks))
+	for j, track := range album.Tracks {
+		goals[j] = PreprocessTrackArtists(track)
+	}
+
+	// queries go from specific to broad, so stop at the first results
+	var candidates []spotify.SimpleAlbum
+	for _, query := range AlbumSearchAttempts(album) {
+		candidates = Session.SearchAlbums(query)
+		if len(candidates) > 0 {
+			break
+		}
+	}
+
+	// only fetch the tracklists of the albums with the closest names
+	sort.SliceStable(candidates, func(a, b int) bool {
+		scoreA, _, _ := albumCompare(goals[0].Album, candidates[a].Name, false, i.Transliterate)
+		scoreB, _, _ := albumCompare(goals[0].Album, candidates[b].Name, false, i.Transliterate)
+		return scoreA < scoreB
+	})
+	if len(candidates) > maxAlbumCandidates {
+		candidates = candidates[:maxAlbumCandidates]
+	}
+
+	var best []*MatchedTrack
+	var bestTracks []spotify.FullTrack
+	bestScore := 1.0
+	for _, candidate := range candidates {
+
+		var full *spotify.FullAlbum
+		var err error
+		for {
+			full, err = Session.Client().GetAlbum(candidate.ID)
+			if Session.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			i.program.Warningf("error getting album %s: %s", candidate.Name, err)
+			continue
+		}
+
+		tracks := albumTracks(full)
+		aligned := AlignTracklist(goals, tracks, i.compareOptions)
+		if score := ScoreAlbum(goals, tracks, aligned); score < bestScore {
+			best, bestTracks, bestScore = aligned, tracks, score
+		}
+
+	}
+
+	if bestScore > thresholdAlbum {
+		i.program.Log("  no album lined up, matching its tracks one by one")
+		return
+	}
+
+	i.program.Logf("  @%1.4f  %s, %d of %d tracks",
+		bestScore, best[0].spotify.Album.Name, len(best), len(goals))
+	for _, mt := range best {
+		i.trackCache[mt.itunes.TrackID] = mt
+		i.matchCache.TrackMap.Store(mt)
+	}
+	key := i.albumKey(album.Tracks[0])
+	i.albumCache[key] = bestTracks
+	i.matchCache.AlbumMap.Store(key, best[0])
+	i.matchCache.SaveCache()
+
+}
+
+// alignCompilation matches the rest of the library tracks on the same
+// compilation as the given match against its tracklist all at once
+func (i *Importer) alignCompilation(mt *MatchedTrack, tracks []spotify.FullTrack) {
+
+	original, ok := i.lib.TracksByID[mt.itunes.TrackID]
+	if !ok {
+		return
+	}
+
+	var goals []*itunes.Track
+	for _, track := range i.lib.Tracks {
+		if track.TrackID == original.TrackID || !sameAlbum(track, original) || i.shouldSkipTrack(track) {
+			continue
+		}
+		if _, ok := i.trackCache[track.TrackID]; ok {
+			continue
+		}
+		if _, ok := i.matchCache.TrackMap[track.PersistentID]; ok {
+			continue
+		}
+		goals = append(goals, PreprocessTrackArtists(track))
+	}
+	if len(goals) == 0 {
+		return
+	}
+
+	var remaining []spotify.FullTrack
+	for _, t := range tracks {
+		if t.ID != mt.spotify.ID {
+			remaining = append(remaining, t)
+		}
+	}
+
+	aligned := AlignTracklist(goals, remaining, i.compareOptions)
+	for _, a := range aligned {
+		i.trackCache[a.itunes.TrackID] = a
+		i.matchCache.TrackMap.Store(a)
+	}
+	i.program.Logf("  matched %d of %d other tracks on the compilation", len(aligned), len(goals))
+
+}
+
+// mappedTrackID finds the spotify id for the given track, using the
+// cached id directly where possible rather than fetching the track
+// details, and returns an empty id if there is no match
+func (i *Importer) mappedTrackID(track *itunes.Track) spotify.ID {
+
+	if cached, ok := i.matchCache.TrackMap[track.PersistentID]; ok {
+		return spotify.ID(cached.SpotifyID)
+	}
+
+	mt := i.getMappedTrack(track.TrackID)
+	if nil == mt || !mt.Valid() {
+		return ""
+	}
+	return mt.spotify.ID
+
+}
+
+// forcedTrack fetches the spotify track that a rule forces the goal to
+func (i *Importer) forcedTrack(goal *itunes.Track, id spotify.ID) *MatchedTrack {
+
+	var track *spotify.FullTrack
+	var err error
+	for {
+		track, err = Session.Client().GetTrack(id)
+		if Session.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err || nil == track {
+		i.program.Warningf("error getting forced track %s: %v", id, err)
+		return nil
+	}
+
+	return &MatchedTrack{
+		itunes:  PreprocessTrackArtists(goal),
+		spotify: track,
+		score:   0,
+	}
+
+}
+
+// albumKey is the AlbumKey of the library track that the given
+// track was made from, since preprocessing changes its artist
+func (i *Importer) albumKey(track *itunes.Track) string {
+	if original, ok := i.lib.TracksByID[track.TrackID]; ok {
+		return AlbumKey(original)
+	}
+	return AlbumKey(track)
+}
+
+// aliasedAlbumTracks fetches the tracks of the spotify album
+// that the album of the goal is aliased to by id, if any
+func (i *Importer) aliasedAlbumTracks(goal *itunes.Track) []spotify.FullTrack {
+
+	alias, ok := activeAliases.Album(goal.Album)
+	if !ok || alias.ID == "" {
+		return nil
+	}
+
+	var album *spotify.FullAlbum
+	var err error
+	for {
+		album, err = Session.Client().GetAlbum(spotify.ID(alias.ID))
+		if Session.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err {
+		i.program.Warningf("error getting aliased album %s: %s", alias.ID, err)
+		return nil
+	}
+
+	aTracks := albumTracks(album)
+	i.albumCache[i.albumKey(goal)] = aTracks
+	return aTracks
+
+}
+
 func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 
 	i.matchNum++
@@ -322,26 +686,55 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 	i.program.Logf("            \n%04d/%04d: %s\n",
 		i.matchNum, i.matchTotal, ItunesCacheString(goal))
 
+	// the rules can force a track to a specific match
+	if id, ok := i.rules.ForcedID(goal); ok {
+		if forced := i.forcedTrack(goal, id); nil != forced {
+			return i.cacheTrack(forced)
+		}
+	}
+
 	// see if it exists in a previous cache
 	cached := i.matchCache.TrackMap.GetMatch(goal)
 	if nil != cached {
 		return i.cacheTrack(cached)
 	}
 
-	// TODO special case
-	if strings.ToLower(goal.Artist) == "taylor swift" {
+	// artists that are not on spotify are not worth searching for
+	if i.rules.IsAbsent(goal) {
+		i.program.Log("  artist is marked as absent from spotify")
+		i.trackCache[itunesTrackID] = nil
 		return nil
 	}
 
 	goal = PreprocessTrackArtists(goal)
 
 	// see if the album was already mapped
-	aTracks, ok := i.albumCache[goal.Album]
+	key := i.albumKey(goal)
+	aTracks, ok := i.albumCache[key]
 	if !ok {
 		// see if it exists in a previous cache
-		a := i.matchCache.AlbumMap.GetMatch(goal.Album)
+		a := i.matchCache.AlbumMap.GetMatch(key)
 		if a != nil {
 			aTracks = albumTracks(a)
+			i.albumCache[key] = aTracks
+		}
+	}
+	if nil == aTracks {
+		aTracks = i.aliasedAlbumTracks(goal)
+	}
+
+	// a cached album is only used if it has tracks by this artist
+	if len(aTracks) > 0 && !albumHasArtist(goal, aTracks, i.Transliterate) {
+		i.program.Log("  cached album has no tracks by this artist, ignoring it")
+		aTracks = nil
+	}
+
+	// the user already chose the album for all of its tracks
+	if fTracks := i.forcedAlbums[key]; len(fTracks) > 0 {
+		forced := i.scoreTracks(fTracks, goal)
+		sort.Sort(byScoreAndDate(forced))
+		if forced[0].score <= thresholdLikely {
+			return i.cacheTrack(forced[0])
 		}
 	}
 
@@ -359,14 +752,38 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 
 	}
 
-	// move on to querying spotify
+	// move on to querying spotify, with the strategies
+	// that have been most successful so far first
 	queryOptions := SearchAttempts(goal)
+	if ci := i.classicalInfo(goal); nil != ci {
+		queryOptions = append(ClassicalSearchAttempts(goal, ci), queryOptions...)
+	}
+	queryOptions = i.queryStats.Plan(queryOptions)
+
+	// an isrc identifies the exact recording, so always try it first
+	if isrc := i.lib.Extras(goal).ISRC; isrc != "" {
+		queryOptions = append([]SearchQuery{{
+			Strategy: "isrc",
+			Query:    fmt.Sprintf("isrc:%s", isrc),
+			Pages:    1,
+		}}, queryOptions...)
+	}
 
+	// each page of results is a request against the budget
+	searches := 0
 	for _, query := range queryOptions {
 
-		results := Session.SearchTracks(query, 2) // fetch 2 pages - max 40 tracks
-		//fmt.Printf("%s %d\n", query, len(results))
+		if searches+query.Pages > searchBudget {
+			i.program.Log("  search budget used up")
+			break
+		}
+		searches += query.Pages
 
+		results := Session.SearchTracks(query.Query, query.Pages)
+		//fmt.Printf("%s %d\n", query.Query, len(results))
+
+		// a query that finds nothing new says nothing about
+		// its strategy, so only scored results are counted
 		if 0 == len(results) {
 			continue
 		}
@@ -389,7 +806,14 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 			continue
 		}
 
-		scored = append(scored, i.scoreTracks(newTracks, goal)...)
+		newScored := i.scoreTracks(newTracks, goal)
+		found := false
+		for _, mt := range newScored {
+			found = found || mt.score <= thresholdMatched
+		}
+		i.queryStats.Record(query.Strategy, found)
+
+		scored = append(scored, newScored...)
 
 		sort.Sort(byScoreAndDate(scored))
 
@@ -398,9 +822,10 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 			matched = append(matched, scored[j])
 		}
 
+		// stop as soon as there is a clear match
 		if len(matched) > 0 {
 
-			if len(matched) == 1 || i.GuessMatching {
+			if len(matched) == 1 || i.GuessMatching || isConfident(matched) {
 				return i.cacheTrack(matched[0])
 			}
 
@@ -409,9 +834,7 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 	}
 
 	// try re-scoring them without the album name
-	for _, mt := range scored {
-		mt.score = TrackCompare(mt.itunes, mt.spotify, i.PreferOriginal, true)
-	}
+	i.rescoreWithoutAlbum(scored)
 
 	sort.Sort(byScoreAndDate(scored))
 
@@ -428,7 +851,20 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 
 	}
 
-	match := i.askMappedTrackSelection(goal, scored)
+	if i.DeferReview {
+		i.program.Log("  queued for review")
+		i.review.Enqueue(goal, scored)
+		i.review.SaveQueue()
+		i.trackCache[itunesTrackID] = nil
+		return nil
+	}
+
+	match, cache := i.chooseMappedTrack(goal, scored)
+	if !cache {
+		// skipped for now, so ask again next time
+		i.trackCache[itunesTrackID] = nil
+		return nil
+	}
 	if match == nil {
 		match = &MatchedTrack{
 			itunes:  goal,
@@ -440,19 +876,75 @@ func (i *Importer) getMappedTrack(itunesTrackID int) *MatchedTrack {
 
 }
 
+// logQueryStats logs how successful each search strategy was
+func (i *Importer) logQueryStats() {
+	if len(i.queryStats) > 0 {
+		i.program.Logf("search strategy matches: %s", i.queryStats)
+	}
+}
+
+// classicalInfo returns the classical metadata of the goal when
+// classical matching is on and it looks like a classical recording
+func (i *Importer) classicalInfo(goal *itunes.Track) *ClassicalInfo {
+	if !i.Classical {
+		return nil
+	}
+	return NewClassicalInfo(goal, i.lib.Extras(goal))
+}
+
+// compareTrack compares the goal and test with the match settings
+func (i *Importer) compareTrack(goal *itunes.Track, test *spotify.FullTrack, ignoreAlbum bool) *ScoreBreakdown {
+	opts := i.compareOptions(goal)
+	opts.IgnoreAlbum = ignoreAlbum
+	return CompareTracks(goal, test, opts)
+}
+
+// compareOptions are the match settings for comparing the goal,
+// which are shared by single tracks and whole album tracklists
+func (i *Importer) compareOptions(goal *itunes.Track) CompareOptions {
+	return CompareOptions{
+		PreferOriginal: i.PreferOriginal,
+		Classical:      i.classicalInfo(goal),
+		Explicit:       i.wantExplicit(goal),
+		Transliterate:  i.Transliterate,
+	}
+}
+
+// rescoreWithoutAlbum scores the tracks again ignoring the album
+// name, keeping the search rank and isrc match from their first score
+func (i *Importer) rescoreWithoutAlbum(scored []*MatchedTrack) {
+
+	for _, mt := range scored {
+		sb := i.compareTrack(mt.itunes, mt.spotify, true)
+		if nil != mt.breakdown {
+			sb.Rank = mt.breakdown.Rank
+			sb.ISRC = mt.breakdown.ISRC
+		}
+		mt.breakdown = sb
+		mt.score = sb.Total()
+	}
+
+}
+
 func (i *Importer) scoreTracks(tracks []spotify.FullTrack, goal *itunes.Track) []*MatchedTrack {
 
 	mapped := make([]*MatchedTrack, len(tracks))
+	isrc := i.lib.Extras(goal).ISRC
 
 	for j := 0; j < len(tracks); j++ {
 
-		vers := float64(j) * 0.025
+		sb := i.compareTrack(goal, &tracks[j], false)
+		sb.Rank = float64(j) * 0.025
+
+		// matching isrc codes are the same recording regardless of naming
+		sb.ISRC = isrc != "" && strings.EqualFold(tracks[j].ExternalIDs["isrc"], isrc)
 
 		mapped[j] = &MatchedTrack{
-			itunes:  goal,
-			spotify: &tracks[j],
-			sAlbum:  nil,
-			score:   vers + TrackCompare(goal, &tracks[j], i.PreferOriginal, false),
+			itunes:    goal,
+			spotify:   &tracks[j],
+			sAlbum:    nil,
+			score:     sb.Total(),
+			breakdown: sb,
 		}
 
 	}
@@ -480,9 +972,11 @@ func (i *Importer) askMappedTrackSelection(goal *itunes.Track, tracks []*Matched
 
 		var options []string
 		for _, mt := range tracks {
-			options = append(options, fmt.Sprintf("%s [%1.4f]",
-				SpotifyCacheString(mt.spotify),
-				mt.score))
+			option := fmt.Sprintf("%s [%1.4f]", SpotifyCacheString(mt.spotify), mt.score)
+			if nil != mt.breakdown {
+				option += "\n      " + mt.breakdown.String()
+			}
+			options = append(options, option)
 		}
 		options = append(options, "none of the above")
 
@@ -497,23 +991,9 @@ func (i *Importer) askMappedTrackSelection(goal *itunes.Track, tracks []*Matched
 	if sel == -1 {
 
 		// search for the term and ask again
-		var results *spotify.SearchResult
-		var err error
-		for {
-			results, err = Session.Client().Search(text, spotify.SearchTypeTrack)
-			if Session.ShouldTryAgain(err) {
-				continue
-			} else if err != nil {
-				return nil
-			}
-			break
-		}
-		var options []*MatchedTrack
-		for j := 0; j < len(results.Tracks.Tracks); j++ {
-			options = append(options, &MatchedTrack{
-				itunes:  goal,
-				spotify: &results.Tracks.Tracks[j],
-			})
+		options, err := i.searchCandidates(goal, text)
+		if nil != err {
+			return nil
 		}
 		return i.askMappedTrackSelection(goal, options)
 	}
@@ -525,3 +1005,95 @@ func (i *Importer) askMappedTrackSelection(goal *itunes.Track, tracks []*Matched
 	return tracks[sel]
 
 }
+
+// chooseMappedTrack asks the user to choose between the given candidates,
+// returning the chosen match and whether the decision should be cached
+func (i *Importer) chooseMappedTrack(goal *itunes.Track, tracks []*MatchedTrack) (*MatchedTrack, bool) {
+
+	var match *MatchedTrack
+	cache := true
+	if i.ReviewScreen {
+		match, cache = i.reviewMappedTrack(goal, tracks)
+	} else {
+		match = i.askMappedTrackSelection(goal, tracks)
+	}
+
+	// every choice is an example to learn a match profile from
+	if cache {
+		if err := i.decisions.Log(goal, match, tracks); nil != err {
+			i.program.Warningf("error logging decision: %s", err)
+		}
+	}
+	return match, cache
+
+}
+
+// reviewMappedTrack shows the review screen for the given candidates,
+// returning the chosen match and whether the decision should be cached
+func (i *Importer) reviewMappedTrack(goal *itunes.Track, tracks []*MatchedTrack) (*MatchedTrack, bool) {
+
+	for {
+
+		res, err := ReviewMatch(goal, tracks)
+		if nil != err {
+			// without a terminal, fall back to asking for the choice
+			i.program.Warningf("cannot start review screen, asking instead: %s", err)
+			i.ReviewScreen = false
+			return i.askMappedTrackSelection(goal, tracks), true
+		}
+		switch res.Action {
+
+		case ReviewSearch:
+			options, err := i.searchCandidates(goal, res.Query)
+			if nil != err {
+				i.program.Warningf("search failed: %s", err)
+				continue
+			}
+			tracks = options
+
+		case ReviewSkip:
+			return nil, false
+
+		case ReviewUnavailable:
+			return nil, true
+
+		default:
+			if res.WholeAlbum {
+				i.forcedAlbums[i.albumKey(goal)] = albumTracks(res.Match.FullAlbum())
+			}
+			return res.Match, true
+
+		}
+
+	}
+
+}
+
+// searchCandidates runs a custom search for the given goal track
+func (i *Importer) searchCandidates(goal *itunes.Track, query string) ([]*MatchedTrack, error) {
+
+	var results *spotify.SearchResult
+	var err error
+	for {
+		results, err = Session.Client().Search(query, spotify.SearchTypeTrack)
+		if Session.ShouldTryAgain(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	var options []*MatchedTrack
+	for j := 0; j < len(results.Tracks.Tracks); j++ {
+		sb := i.compareTrack(goal, &results.Tracks.Tracks[j], false)
+		options = append(options, &MatchedTrack{
+			itunes:    goal,
+			spotify:   &results.Tracks.Tracks[j],
+			score:     sb.Total(),
+			breakdown: sb,
+		})
+	}
+	return options, nil
+
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+func TestRescoreWithoutAlbum(t *testing.T) {
+
+	goal := &itunes.Track{PersistentID: "goal", Name: "First Song", Artist: "The Band", Album: "First Album"}
+	mapper := &Importer{
+		lib: &Library{
+			Library: &itunes.Library{},
+			extras:  map[string]*TrackExtras{"goal": {ISRC: "USABC0000001"}},
+		},
+	}
+
+	// the same recording under another title and album,
+	// and another recording that only differs by album
+	var tracks []spotify.FullTrack
+	for _, name := range []string{"Untitled", "First Song"} {
+		test := spotify.FullTrack{}
+		test.ID = spotify.ID(name)
+		test.Name = name
+		test.Artists = []spotify.SimpleArtist{{Name: "The Band"}}
+		test.Album.Name = "Something Else Entirely"
+		test.Popularity = 100
+		tracks = append(tracks, test)
+	}
+	tracks[0].ExternalIDs = spotify.ExternalID{"isrc": "usabc0000001"}
+	tracks[1].Popularity = 0
+
+	scored := mapper.scoreTracks(tracks, goal)
+	if !scored[0].breakdown.ISRC || scored[0].score > thresholdMatched {
+		t.Fatalf("expected the isrc match to pass, got %s", scored[0].breakdown)
+	}
+	if scored[1].score <= thresholdMatched {
+		t.Fatalf("expected the other album not to pass, got %s", scored[1].breakdown)
+	}
+
+	mapper.rescoreWithoutAlbum(scored)
+	if !scored[0].breakdown.ISRC || scored[0].breakdown.Rank != 0 || scored[0].score > thresholdMatched {
+		t.Errorf("expected the isrc match to still pass without the album, got %s", scored[0].breakdown)
+	}
+	if scored[1].breakdown.Rank != 0.025 || scored[1].breakdown.ISRC {
+		t.Errorf("expected the search rank to be kept, got %s", scored[1].breakdown)
+	}
+	if scored[1].score > thresholdMatched {
+		t.Errorf("expected the other recording to pass without the album, got %s", scored[1].breakdown)
+	}
+
+}
+
+func TestAlignCompilation(t *testing.T) {
+
+	goals, tracks := testAlbum("first song", "second song", "third song", "fourth song", "fifth song")
+	for j, goal := range goals {
+		goal.PersistentID = tracks[j].ID.String()
+		goal.AlbumArtist = "Various Artists"
+	}
+	goals[3].Podcast = true
+	other := &itunes.Track{TrackID: 10, PersistentID: "other", Name: "fifth song", Artist: "the band", Album: "second album"}
+
+	lib := &Library{Library: &itunes.Library{
+		Tracks:     append(goals, other),
+		TracksByID: make(map[int]*itunes.Track),
+	}}
+	for _, track := range lib.Tracks {
+		lib.TracksByID[track.TrackID] = track
+	}
+
+	mapper := &Importer{
+		lib:        lib,
+		rules:      &MatchRules{},
+		matchCache: &MatchCache{TrackMap: make(TrackMap)},
+		trackCache: make(map[int]*MatchedTrack),
+	}
+	// the third track was matched by an earlier import
+	mapper.matchCache.TrackMap["third song"] = &CachedTrackMatch{SpotifyID: "elsewhere"}
+
+	mt := &MatchedTrack{itunes: goals[0], spotify: &tracks[0]}
+	mapper.alignCompilation(mt, tracks)
+
+	for _, j := range []int{1, 4} {
+		aligned, ok := mapper.trackCache[goals[j].TrackID]
+		if !ok || aligned.spotify.ID != tracks[j].ID {
+			t.Errorf("expected %s to be aligned with itself, got %+v", goals[j].Name, aligned)
+		}
+		if mapper.matchCache.TrackMap[goals[j].PersistentID].SpotifyID != tracks[j].ID.String() {
+			t.Errorf("expected the alignment of %s to be cached", goals[j].Name)
+		}
+	}
+	for _, track := range []*itunes.Track{goals[0], goals[2], goals[3], other} {
+		if aligned, ok := mapper.trackCache[track.TrackID]; ok {
+			t.Errorf("expected %s not to be aligned, got %+v", track.Name, aligned)
+		}
+	}
+	if cached := mapper.matchCache.TrackMap["third song"]; cached.SpotifyID != "elsewhere" {
+		t.Errorf("expected an earlier match to be kept, got %+v", cached)
+	}
+
+}
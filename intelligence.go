@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	itunes "github.com/rydrman/go-itunes-library"
-	"github.com/xrash/smetrics"
 	"github.com/zmb3/spotify"
 )
 
@@ -21,10 +20,15 @@ const (
 	simpleEffect  = 0.05
 	complexEffect = 0.95
 
-	titleWeight      = 0.5
-	artistWeight     = 0.3
-	albumWeight      = 0.1
-	popularityWeight = 0.1
+	titleWeight        = 0.5
+	artistWeight       = 0.3
+	albumWeight        = 0.1
+	popularityWeight   = 0.1
+	compilationPenalty = 0.1
+
+	// unplayablePenalty is added to tracks that cannot
+	// be played in the market they were searched for
+	unplayablePenalty = 0.2
 )
 
 // cleanReplacements are regexs that attempt
@@ -59,6 +63,7 @@ var simpleReplacements = map[string][]*re.Regexp{
 		re.MustCompile(` - (\w+ )?from .*$`),
 		re.MustCompile(` - single version.*$`),
 		re.MustCompile(` - radio edit.*$`),
+		re.MustCompile(` - ([0-9]+ )?(digital )?remaster.*$`),
 	},
 }
 
@@ -77,20 +82,22 @@ var complexReplacements = map[string][]*re.Regexp{
 func PreprocessTrackArtists(goal *itunes.Track) *itunes.Track {
 
 	newTrack := itunes.Track(*goal)
+	newTrack.Artist = activeAliases.ArtistName(newTrack.Artist)
+	newTrack.Album = activeAliases.AlbumName(newTrack.Album)
 
 	featureRe := re.MustCompile(`[\s\(\[](feat\.?|ft\.?|featuring)\s([\s\w,&]*)`)
 
 	for groups := featureRe.FindStringSubmatch(newTrack.Name); len(groups) > 0; groups = featureRe.FindStringSubmatch(newTrack.Name) {
 
 		newTrack.Name = strings.Replace(newTrack.Name, groups[0], "", 1)
-		newTrack.Artist += " & " + groups[2]
+		newTrack.Artist += " & " + activeAliases.ArtistName(groups[2])
 
 	}
 
 	for groups := featureRe.FindStringSubmatch(newTrack.Artist); len(groups) > 0; groups = featureRe.FindStringSubmatch(newTrack.Artist) {
 
 		newTrack.Artist = strings.Replace(newTrack.Artist, groups[0], "", 1)
-		newTrack.Artist += " & " + groups[2]
+		newTrack.Artist += " & " + activeAliases.ArtistName(groups[2])
 
 	}
 
@@ -98,28 +105,195 @@ func PreprocessTrackArtists(goal *itunes.Track) *itunes.Track {
 
 }
 
+// ScoreBreakdown explains a track comparison score, with the weighted
+// part that each field contributed, the replacement tier that decided
+// each string comparison and any special string rules that fired
+type ScoreBreakdown struct {
+	Title            float64
+	Artist           float64
+	Album            float64
+	Popularity       float64
+	Compilation      float64
+	Rank             float64 `json:",omitempty"`
+	ISRC             bool    `json:",omitempty"`
+	Unplayable       bool    `json:",omitempty"`
+	ExplicitMismatch bool    `json:",omitempty"`
+
+	TitleTier  string
+	ArtistTier string
+	AlbumTier  string
+	Rules      []string `json:",omitempty"`
+
+	// the unweighted comparison values behind this breakdown
+	features []float64
+}
+
+// Features are the unweighted comparison values behind this breakdown,
+// in the order of the weights in a MatchProfile, or nil if unknown
+func (sb *ScoreBreakdown) Features() []float64 {
+	return sb.features
+}
+
+// Total is the full score, which is what TrackCompare used to return
+func (sb *ScoreBreakdown) Total() float64 {
+
+	total := sb.Title + sb.Artist + sb.Album + sb.Popularity + sb.Compilation + sb.Rank
+	if sb.ISRC {
+		total = math.Min(total, sb.Rank)
+	}
+	if sb.Unplayable {
+		total += unplayablePenalty
+	}
+	if sb.ExplicitMismatch {
+		total += explicitPenalty
+	}
+	return total
+
+}
+
+func (sb *ScoreBreakdown) String() string {
+
+	str := fmt.Sprintf("title %.3f (%s), artist %.3f (%s), album %.3f (%s), popularity %.3f",
+		sb.Title, sb.TitleTier, sb.Artist, sb.ArtistTier, sb.Album, sb.AlbumTier, sb.Popularity)
+	if sb.Compilation > 0 {
+		str += fmt.Sprintf(", compilation %.3f", sb.Compilation)
+	}
+	if sb.Rank > 0 {
+		str += fmt.Sprintf(", rank %.3f", sb.Rank)
+	}
+	if sb.ISRC {
+		str += ", isrc match"
+	}
+	if sb.Unplayable {
+		str += ", unplayable"
+	}
+	if sb.ExplicitMismatch {
+		str += ", explicit mismatch"
+	}
+	if len(sb.Rules) > 0 {
+		str += fmt.Sprintf(" [%s]", strings.Join(sb.Rules, ", "))
+	}
+	return str
+
+}
+
+// CompareOptions change how CompareTracks scores a candidate
+type CompareOptions struct {
+	PreferOriginal bool
+	IgnoreAlbum    bool
+
+	// Classical holds the classical metadata of the goal
+	// when it should be compared as a classical recording
+	Classical *ClassicalInfo
+
+	// Explicit is whether the explicit or clean version
+	// is wanted, or nil if either will do
+	Explicit *bool
+
+	// Transliterate compares names written in other scripts in latin letters
+	Transliterate bool
+}
+
 // TrackCompare intelligently compares the itunes track to the spotify track and
-// returns a number from 0 to 1 0 being exaclty the same to 1 being totally different
-func TrackCompare(goal *itunes.Track, test *spotify.FullTrack, preferOriginal, ignoreAlbum bool) float64 {
+// returns a breakdown whose total is a number from 0 to 1, 0 being exaclty the
+// same to 1 being totally different
+func TrackCompare(goal *itunes.Track, test *spotify.FullTrack, preferOriginal, ignoreAlbum bool) *ScoreBreakdown {
+	return CompareTracks(goal, test, CompareOptions{
+		PreferOriginal: preferOriginal,
+		IgnoreAlbum:    ignoreAlbum,
+	})
+}
+
+// CompareTracks is TrackCompare with all of the comparison options
+func CompareTracks(goal *itunes.Track, test *spotify.FullTrack, opts CompareOptions) *ScoreBreakdown {
 
-	score := 0.0
+	sb := &ScoreBreakdown{}
+	profile := activeProfile
+	var score float64
+	var rule string
 
 	// first compare title
-	score += titleWeight * TitleCompare(goal.Name, test.Name)
+	if nil != opts.Classical {
+		score, sb.TitleTier, rule = opts.Classical.titleCompare(goal, test, opts.Transliterate)
+	} else {
+		score, sb.TitleTier, rule = titleCompare(goal.Name, test.Name, opts.Transliterate)
+	}
+	sb.Title = profile.TitleWeight * score
+	sb.features = append(sb.features, score)
+	if rule != "" {
+		sb.Rules = append(sb.Rules, "title "+rule)
+	}
 
 	// then artist
-	score += artistWeight * ArtistCompare(goal.Artist, artist(test))
+	if nil != opts.Classical {
+		score, sb.ArtistTier, rule = opts.Classical.artistCompare(goal, test, opts.Transliterate)
+	} else {
+		score, sb.ArtistTier, rule = artistCompare(goal.Artist, artist(test), opts.Transliterate)
+	}
+	if activeAliases.MatchesArtist(goal.Artist, test) {
+		score, sb.ArtistTier, rule = 0, "alias", ""
+	}
+	sb.Artist = profile.ArtistWeight * score
+	sb.features = append(sb.features, score)
+	if rule != "" {
+		sb.Rules = append(sb.Rules, "artist "+rule)
+	}
 
-	// then album
-	score += albumWeight * AlbumCompare(goal.Album, test.Album.Name, ignoreAlbum)
-	if preferOriginal && test.Album.AlbumType == "consolidation" {
-		score += 0.1
+	// then album, where the original release of a track from a
+	// compilation by a single artist will have a different name
+	various := isVariousArtists(goal.AlbumArtist)
+	ignoreAlbum := opts.IgnoreAlbum || (opts.PreferOriginal && goal.Compilation && !various)
+	score, sb.AlbumTier, rule = albumCompare(goal.Album, test.Album.Name, ignoreAlbum, opts.Transliterate)
+	if !opts.IgnoreAlbum && activeAliases.MatchesAlbum(goal.Album, test) {
+		score, sb.AlbumTier, rule = 0, "alias", ""
+	}
+	sb.Album = profile.AlbumWeight * score
+	sb.features = append(sb.features, score)
+	if rule != "" {
+		sb.Rules = append(sb.Rules, "album "+rule)
+	}
+
+	// various artists compilations have no original release
+	// to prefer, so the compilation itself is the best match
+	compilation := 0.0
+	if opts.PreferOriginal && !various && test.Album.AlbumType == "compilation" {
+		compilation = 1.0
 	}
 
 	// account for popularity
-	score += (1.0 - float64(test.Popularity)/100.0) * popularityWeight
+	popularity := 1.0 - float64(test.Popularity)/100.0
+	sb.Popularity = popularity * profile.PopularityWeight
+	sb.features = append(sb.features, popularity)
 
-	return score
+	sb.Compilation = compilation * profile.CompilationPenalty
+	sb.features = append(sb.features, compilation)
+
+	// a playable version of the track in the user's market is better
+	sb.Unplayable = nil != test.IsPlayable && !*test.IsPlayable
+
+	// as is the wanted explicit or clean version, if there is one
+	sb.ExplicitMismatch = nil != opts.Explicit && *opts.Explicit != test.Explicit
+
+	return sb
+
+}
+
+// specialRule is a string that makes two names unequal when it
+// only appears in one of them, eg: a live version of a song
+type specialRule struct {
+	Name  string
+	Regex *re.Regexp
+}
+
+// specialRuleMismatch finds the first rule that only one of a and b match
+func specialRuleMismatch(a, b string, rules []specialRule) string {
+
+	for _, rule := range rules {
+		if rule.Regex.MatchString(a) != rule.Regex.MatchString(b) {
+			return rule.Name
+		}
+	}
+	return ""
 
 }
 
@@ -127,26 +301,21 @@ func TrackCompare(goal *itunes.Track, test *spotify.FullTrack, preferOriginal, i
 // of a match, returns a probability float (can be greater than 1, but that
 // means the match is even less likely)
 func TitleCompare(a, b string) float64 {
+	score, _, _ := titleCompare(a, b, false)
+	return score
+}
 
-	a = strings.Trim(strings.ToLower(a), " ")
-	b = strings.Trim(strings.ToLower(b), " ")
-
-	specialStrings := []*re.Regexp{
-		re.MustCompile(`(^|\s+)live(\s+|$)`),
-		re.MustCompile(`karaoke`),
-		re.MustCompile(`instrumental`),
-		re.MustCompile(`cover`),
-	}
-
-	for _, regex := range specialStrings {
+func titleCompare(a, b string, transliterateNames bool) (float64, string, string) {
 
-		if regex.MatchString(a) != regex.MatchString(b) {
-			return 1.0 / titleWeight
-		}
+	a = compareName(a, transliterateNames)
+	b = compareName(b, transliterateNames)
 
+	// a different version is less likely rather than never a match
+	score, tier := sCompareScore(a, b)
+	if penalty, rule := versionMismatch(a, b); penalty > 0 {
+		return score + penalty, "rule", rule
 	}
-
-	return SCompareScore(a, b)
+	return score, tier, ""
 
 }
 
@@ -157,29 +326,28 @@ func TitleCompare(a, b string) float64 {
 // simpleCompare will foregoe string comparisons in an attempt to
 // only look for albums that are not obviously problematic
 func AlbumCompare(a, b string, simpleCompare bool) float64 {
+	score, _, _ := albumCompare(a, b, simpleCompare, false)
+	return score
+}
+
+var albumSpecialRules = []specialRule{
+	{"cast", re.MustCompile(`(^|\s+)cast(\s+|$)`)},
+	{"soundtrack", re.MustCompile(`soundtrack`)},
+}
 
-	a = strings.Trim(strings.ToLower(a), " ")
-	b = strings.Trim(strings.ToLower(b), " ")
+func albumCompare(a, b string, simpleCompare, transliterateNames bool) (float64, string, string) {
+
+	a = activeAliases.AlbumName(a)
+	a = compareName(a, transliterateNames)
+	b = compareName(b, transliterateNames)
 
 	// empty string makes us unsure but not devastatingly
 	if a == "" || b == "" {
-		return 0.5
+		return 0.5, "empty", ""
 	}
 
-	specialStrings := []*re.Regexp{
-		re.MustCompile(`(^|\s+)live(\s+|$)`),
-		re.MustCompile(`karaoke`),
-		re.MustCompile(`(^|\s+)cast(\s+|$)`),
-		re.MustCompile(`soundtrack`),
-		re.MustCompile(`cover`),
-	}
-
-	for _, regex := range specialStrings {
-
-		if regex.MatchString(a) != regex.MatchString(b) {
-			return 1.0 / albumWeight
-		}
-
+	if rule := specialRuleMismatch(a, b, albumSpecialRules); rule != "" {
+		return rejection(activeProfile.AlbumWeight, albumWeight), "rule", rule
 	}
 
 	// these regular expressions denote album titles that
@@ -204,11 +372,19 @@ func AlbumCompare(a, b string, simpleCompare bool) float64 {
 
 	}
 
+	penalty, rule := versionMismatch(a, b)
 	if simpleCompare {
-		return 0.0
+		if penalty > 0 {
+			return penalty, "rule", rule
+		}
+		return 0.0, "ignored", ""
 	}
 
-	return SCompareScore(a, b)
+	score, tier := sCompareScore(a, b)
+	if penalty > 0 {
+		return score + penalty, "rule", rule
+	}
+	return score, tier, ""
 
 }
 
@@ -216,22 +392,24 @@ func AlbumCompare(a, b string, simpleCompare bool) float64 {
 // of a match, returns a probability float (can be greater than 1, but that
 // means the match is even less likely)
 func ArtistCompare(a, b string) float64 {
+	score, _, _ := artistCompare(a, b, false)
+	return score
+}
 
-	a = strings.Trim(strings.ToLower(a), " ")
-	b = strings.Trim(strings.ToLower(b), " ")
-
-	specialStrings := []*re.Regexp{
-		re.MustCompile(`karaoke`),
-		re.MustCompile(`(^|\s+)cast(\s+|$)`),
-		re.MustCompile(`soundtrack`),
-	}
+var artistSpecialRules = []specialRule{
+	{"karaoke", re.MustCompile(`karaoke`)},
+	{"cast", re.MustCompile(`(^|\s+)cast(\s+|$)`)},
+	{"soundtrack", re.MustCompile(`soundtrack`)},
+}
 
-	for _, regex := range specialStrings {
+func artistCompare(a, b string, transliterateNames bool) (float64, string, string) {
 
-		if regex.MatchString(a) != regex.MatchString(b) {
-			return 1.0 / artistWeight
-		}
+	a = activeAliases.ArtistName(a)
+	a = compareName(a, transliterateNames)
+	b = compareName(b, transliterateNames)
 
+	if rule := specialRuleMismatch(a, b, artistSpecialRules); rule != "" {
+		return rejection(activeProfile.ArtistWeight, artistWeight), "rule", rule
 	}
 
 	// sort all strings alphabetically for better chance
@@ -244,18 +422,30 @@ func ArtistCompare(a, b string) float64 {
 	a = strings.Join(partsA, " ")
 	b = strings.Join(partsB, " ")
 
-	return SCompareScore(a, b)
+	score, tier := sCompareScore(a, b)
+	return score, tier, ""
 
 }
 
 // SCompareScore returns a score to compare the given strings
 // based on basic string compare methods and common title deviants
 func SCompareScore(a, b string) float64 {
+	score, _ := sCompareScore(a, b)
+	return score
+}
+
+// sCompareScore also returns the tier of replacements (exact, raw,
+// clean, simple or complex) that produced the final score
+func sCompareScore(a, b string) (float64, string) {
 
 	score := wagnerFischerRelative(a, b, 1, 1, 1)
+	tier := "raw"
+	if score == 0 {
+		tier = "exact"
+	}
 
 	if score < cleanEffect {
-		return score
+		return score, tier
 	}
 
 	for r, options := range cleanReplacements {
@@ -265,13 +455,12 @@ func SCompareScore(a, b string) float64 {
 			a = option.ReplaceAllString(a, r)
 			b = option.ReplaceAllString(b, r)
 
-			score = math.Min(
-				score,
-				cleanEffect+wagnerFischerRelative(a, b, 1, 1, 1),
-			)
+			if next := cleanEffect + wagnerFischerRelative(a, b, 1, 1, 1); next < score {
+				score, tier = next, "clean"
+			}
 
 			if score == cleanEffect {
-				return cleanEffect
+				return cleanEffect, tier
 			}
 
 		}
@@ -279,7 +468,7 @@ func SCompareScore(a, b string) float64 {
 	}
 
 	if score < simpleEffect {
-		return score
+		return score, tier
 	}
 
 	for r, options := range simpleReplacements {
@@ -289,13 +478,12 @@ func SCompareScore(a, b string) float64 {
 			a = option.ReplaceAllString(a, r)
 			b = option.ReplaceAllString(b, r)
 
-			score = math.Min(
-				score,
-				simpleEffect+wagnerFischerRelative(a, b, 1, 1, 1),
-			)
+			if next := simpleEffect + wagnerFischerRelative(a, b, 1, 1, 1); next < score {
+				score, tier = next, "simple"
+			}
 
 			if score == simpleEffect {
-				return simpleEffect
+				return simpleEffect, tier
 			}
 
 		}
@@ -303,7 +491,7 @@ func SCompareScore(a, b string) float64 {
 	}
 
 	if score < complexEffect {
-		return score
+		return score, tier
 	}
 
 	for r, options := range complexReplacements {
@@ -313,117 +501,124 @@ func SCompareScore(a, b string) float64 {
 			a = option.ReplaceAllString(a, r)
 			b = option.ReplaceAllString(b, r)
 
-			score = math.Min(
-				score,
-				complexEffect+wagnerFischerRelative(a, b, 1, 1, 1),
-			)
+			if next := complexEffect + wagnerFischerRelative(a, b, 1, 1, 1); next < score {
+				score, tier = next, "complex"
+			}
 
 			if score == complexEffect {
-				return complexEffect
+				return complexEffect, tier
 			}
 
 		}
 
 	}
 
-	return score
+	return score, tier
 
 }
 
 func wagnerFischerRelative(a, b string, icost, dcost, scost int) float64 {
 
-	score := float64(smetrics.WagnerFischer(a, b, icost, dcost, scost))
-	longest := math.Max(float64(len(a)), float64(len(b)))
+	runesA, runesB := []rune(a), []rune(b)
+	longest := math.Max(float64(len(runesA)), float64(len(runesB)))
+	if longest == 0 {
+		return 0
+	}
+	score := float64(wagnerFischer(runesA, runesB, icost, dcost, scost))
 	return score / longest
 
 }
 
-// SearchAttempts returns a list of strings
-// to try in searching for this track
-func SearchAttempts(goal *itunes.Track) []string {
+// searchForm is a track name, artist and album
+// normalized to some degree for searching
+type searchForm struct {
+	Suffix string
+	Name   string
+	Artist string
+	Album  string
+}
+
+// searchStrategies build the queries of SearchAttempts, the most
+// selective first. Field scoped queries are precise enough that
+// a single page of results is all that is worth fetching
+var searchStrategies = []struct {
+	Name  string
+	Pages int
+	Query func(f searchForm) string
+}{
+	{"track artist album", 1, func(f searchForm) string {
+		return fmt.Sprintf(`track:"%s" artist:"%s" album:"%s"`, f.Name, f.Artist, f.Album)
+	}},
+	{"track artist", 1, func(f searchForm) string {
+		return fmt.Sprintf(`track:"%s" artist:"%s"`, f.Name, f.Artist)
+	}},
+	{"quoted artist album", 2, func(f searchForm) string {
+		return fmt.Sprintf(`"%s" "%s" "%s"`, f.Name, f.Artist, f.Album)
+	}},
+	{"quoted artist", 2, func(f searchForm) string {
+		return fmt.Sprintf(`"%s" "%s"`, f.Name, f.Artist)
+	}},
+	{"track", 1, func(f searchForm) string {
+		return fmt.Sprintf(`track:"%s"`, f.Name)
+	}},
+	{"quoted", 2, func(f searchForm) string {
+		return fmt.Sprintf(`"%s"`, f.Name)
+	}},
+}
 
-	normName := strings.ToLower(goal.Name)
-	normArtist := strings.ToLower(goal.Artist)
-	normAlbum := strings.ToLower(goal.Album)
+// SearchAttempts returns the queries to try in searching for
+// this track, with the most selective field scoped ones first
+func SearchAttempts(goal *itunes.Track) []SearchQuery {
 
-	queries := []string{
-		fmt.Sprintf(`"%s"`, normName),
-		fmt.Sprintf(`"%s" "%s"`, normName, normArtist),
-		fmt.Sprintf(`track:"%s"`, normName),
-		fmt.Sprintf(`track:"%s" artist:"%s"`, normName, normArtist),
+	norm := searchForm{
+		Name:   strings.ToLower(normalizeText(goal.Name)),
+		Artist: strings.ToLower(normalizeText(activeAliases.ArtistName(goal.Artist))),
+		Album:  strings.ToLower(normalizeText(activeAliases.AlbumName(goal.Album))),
 	}
 
-	cleanName := normName
-	cleanArtist := normArtist
-	cleanAlbum := normAlbum
+	clean := norm
+	clean.Suffix = " clean"
 	for r, options := range cleanReplacements {
 
 		for _, option := range options {
 
-			cleanName = option.ReplaceAllString(cleanName, r)
-			cleanArtist = option.ReplaceAllString(cleanArtist, r)
-			cleanAlbum = option.ReplaceAllString(cleanAlbum, r)
+			clean.Name = option.ReplaceAllString(clean.Name, r)
+			clean.Artist = option.ReplaceAllString(clean.Artist, r)
+			clean.Album = option.ReplaceAllString(clean.Album, r)
 
 		}
 
 	}
 
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s"`, cleanName))
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s" artist:"%s"`, cleanName, cleanArtist))
-
-	simpleName := cleanName
-	simpleArtist := cleanArtist
-	simpleAlbum := cleanAlbum
+	simple := clean
+	simple.Suffix = " simple"
 	for r, options := range simpleReplacements {
 
 		for _, option := range options {
 
-			simpleName = option.ReplaceAllString(simpleName, r)
-			simpleArtist = option.ReplaceAllString(simpleArtist, r)
-			simpleAlbum = option.ReplaceAllString(simpleAlbum, r)
+			simple.Name = option.ReplaceAllString(simple.Name, r)
+			simple.Artist = option.ReplaceAllString(simple.Artist, r)
+			simple.Album = option.ReplaceAllString(simple.Album, r)
 
 		}
 
 	}
 
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s"`, simpleName))
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s" artist:"%s"`, simpleName, simpleArtist))
-
-	queries = append(
-		queries, fmt.Sprintf(`"%s"`, cleanName))
-	queries = append(
-		queries, fmt.Sprintf(`"%s" "%s"`, cleanName, cleanArtist))
-	queries = append(
-		queries, fmt.Sprintf(`"%s"`, simpleName))
-	queries = append(
-		queries, fmt.Sprintf(`"%s" "%s"`, simpleName, simpleArtist))
-
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s" artist:"%s" album:"%s"`, normName, normArtist, normAlbum))
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s" artist:"%s" album:"%s"`, cleanName, cleanArtist, cleanAlbum))
-	queries = append(
-		queries, fmt.Sprintf(`track:"%s" artist:"%s" album:"%s"`, simpleName, simpleArtist, simpleAlbum))
-
-	queries = append(
-		queries, fmt.Sprintf(`"%s" "%s" "%s"`, normName, normArtist, normAlbum))
-	queries = append(
-		queries, fmt.Sprintf(`"%s" "%s" "%s"`, cleanName, cleanArtist, cleanAlbum))
-	queries = append(
-		queries, fmt.Sprintf(`"%s" "%s" "%s"`, simpleName, simpleArtist, simpleAlbum))
-
-	// sort out duplicates
-	var ret []string
-	for _, q := range queries {
-		if !StringInSlice(q, ret) {
-			ret = append(ret, q)
+	var queries []SearchQuery
+	for _, strategy := range searchStrategies {
+		for _, f := range []searchForm{norm, clean, simple} {
+			// an empty album would only narrow the search to singles
+			if f.Album == "" && strings.Contains(strategy.Name, "album") {
+				continue
+			}
+			queries = append(queries, SearchQuery{
+				Strategy: strategy.Name + f.Suffix,
+				Query:    strategy.Query(f),
+				Pages:    strategy.Pages,
+			})
 		}
 	}
 
-	return ret
+	return uniqueQueries(queries)
 
 }
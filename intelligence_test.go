@@ -1,6 +1,12 @@
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
 
 func TestCompareTitle(t *testing.T) {
 
@@ -18,6 +24,31 @@ func TestCompareTitle(t *testing.T) {
 
 }
 
+func TestVersionCompare(t *testing.T) {
+
+	vi := ClassifyVersion("One More Time (Tiësto Extended Remix)")
+	if !vi.Remix || !vi.Extended || vi.Remixer != "tiësto" {
+		t.Errorf("expected an extended remix by tiësto, got %+v", vi)
+	}
+
+	vi = ClassifyVersion("Help! - Remastered 2009")
+	if !vi.Remaster || vi.RemasterYear != 2009 {
+		t.Errorf("expected a 2009 remaster, got %+v", vi)
+	}
+
+	remastered := TitleCompare("help! - remastered 2009", "help! - 2015 remaster")
+	remixed := TitleCompare("one more time (tiesto remix)", "one more time (armand van helden remix)")
+	live := TitleCompare("one more time", "one more time - live")
+	if remastered > remixed || remixed > live {
+		t.Errorf("expected a remaster (%f) to be closer than a remix (%f) and a remix than a live version (%f)",
+			remastered, remixed, live)
+	}
+	if remastered > thresholdMatched {
+		t.Errorf("a different remaster year should still match: %f", remastered)
+	}
+
+}
+
 func TestCompareArtist(t *testing.T) {
 
 	var score float64
@@ -96,3 +127,67 @@ func TestSCompareScore(t *testing.T) {
 	}
 
 }
+
+func TestScoreBreakdown(t *testing.T) {
+
+	goal := &itunes.Track{Name: "first song", Artist: "the band", Album: "first album"}
+	test := &spotify.FullTrack{}
+	test.Name = "first song live"
+	test.Artists = []spotify.SimpleArtist{{Name: "the band"}}
+	test.Album.Name = "first album"
+	test.Popularity = 50
+
+	sb := TrackCompare(goal, test, false, false)
+	if sb.TitleTier != "rule" || len(sb.Rules) != 1 || sb.Rules[0] != "title live" {
+		t.Errorf("expected the live rule to fire on the title, got %s", sb)
+	}
+	if sb.ArtistTier != "exact" || sb.Artist != 0 {
+		t.Errorf("expected an exact artist match, got %s", sb)
+	}
+
+	sb.Rank = 0.05
+	sb.ISRC = true
+	if sb.Total() != sb.Rank {
+		t.Error("an isrc match should only leave the rank in the score")
+	}
+
+}
+
+func TestSearchPlan(t *testing.T) {
+
+	goal := &itunes.Track{Name: "First Song", Artist: "Some Band", Album: "An Album"}
+	queries := SearchAttempts(goal)
+	if queries[0].Query != `track:"first song" artist:"some band" album:"an album"` {
+		t.Errorf("expected the most selective query first, got %s", queries[0].Query)
+	}
+
+	goal.Album = ""
+	for _, q := range SearchAttempts(goal) {
+		if strings.Contains(q.Query, `album:""`) {
+			t.Errorf("expected no album queries without an album, got %s", q.Query)
+		}
+	}
+
+	// a single miss keeps the most selective query first
+	stats := make(QueryStats)
+	stats.Record(queries[0].Strategy, false)
+	stats.Record("quoted", true)
+	planned := stats.Plan(queries)
+	for j := range queries {
+		if planned[j].Strategy != queries[j].Strategy {
+			t.Errorf("expected the static order after a single miss, got %s at %d", planned[j].Strategy, j)
+			break
+		}
+	}
+
+	stats = make(QueryStats)
+	for j := 0; j < minStrategyTries; j++ {
+		stats.Record(queries[0].Strategy, false)
+		stats.Record("quoted", true)
+	}
+	planned = stats.Plan(queries)
+	if planned[0].Strategy != "quoted" || planned[len(planned)-1].Strategy != queries[0].Strategy {
+		t.Errorf("expected successful strategies first and failing ones last, got %s first", planned[0].Strategy)
+	}
+
+}
@@ -1,13 +1,110 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
-
-	itunes "github.com/rydrman/go-itunes-library"
 )
 
+// command is a mode of operation for this utility, which is
+// run once logged in and after a library has been read
+type command struct {
+	Name        string
+	Description string
+	Run         func(program *SimpleCommandProgram, lib *Library)
+}
+
+// commands are all of the available modes, selected by the first
+// command line argument or by asking the user if none was given
+var commands = []command{
+	{
+		Name:        "import",
+		Description: "import the library into spotify",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewImporter(program, lib).Run()
+		},
+	},
+	{
+		Name:        "reverse",
+		Description: "export spotify playlists as itunes xml and m3u files",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewReverseExporter(program, lib).Run()
+		},
+	},
+	{
+		Name:        "export",
+		Description: "export matched spotify uris to files without changing your account",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewExporter(program, lib).Run()
+		},
+	},
+	{
+		Name:        "sync",
+		Description: "sync playlist changes both ways since the last sync",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewSyncer(program, lib).Run()
+		},
+	},
+	{
+		Name:        "watch",
+		Description: "keep syncing whenever the library file changes",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			if w := NewWatcher(program, lib); nil != w {
+				w.Run()
+			}
+		},
+	},
+	{
+		Name:        "web",
+		Description: "review uncertain matches in your browser",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewReviewServer(program, lib).Run()
+		},
+	},
+	{
+		Name:        "review",
+		Description: "review the tracks queued during an import",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewReviewer(program, lib).Run()
+		},
+	},
+	{
+		Name:        "explain",
+		Description: "explain how a track is scored against its candidates",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewExplainer(program, lib).Run()
+		},
+	},
+	{
+		Name:        "learn",
+		Description: "learn a match profile from your manual match decisions",
+		Run: func(program *SimpleCommandProgram, lib *Library) {
+			NewLearner(program, lib).Run()
+		},
+	},
+}
+
+func selectCommand(program *SimpleCommandProgram) command {
+
+	if len(os.Args) > 1 {
+		for _, c := range commands {
+			if c.Name == os.Args[1] {
+				return c
+			}
+		}
+		program.Errorf("unknown command: %s", os.Args[1])
+		os.Exit(1)
+	}
+
+	var options []string
+	for _, c := range commands {
+		options = append(options, fmt.Sprintf("%s: %s", c.Name, c.Description))
+	}
+	return commands[program.AskOptionDefault("what would you like to do", options, 0)]
+
+}
+
 func main() {
 
 	program := &SimpleCommandProgram{}
@@ -16,6 +113,8 @@ func main() {
 	program.Log("Welcome to the iTunes to Spotify utility!")
 	program.Log("at any time you can exit by using ctrl+c")
 
+	cmd := selectCommand(program)
+
 	if "" == clientID || "" == clientSecret {
 		program.Error("app identifiers not found (clientID, clientSecret)")
 	}
@@ -60,30 +159,40 @@ func main() {
 	////////////
 	// read the itunes library
 	////////////
-	var lib *itunes.Library
+	var lib *Library
 	for {
-		fileName := program.AskStringDefault("enter path to itunes library XML file", "")
+		fileName := program.AskStringDefault("enter path to a library file (iTunes, Rhythmbox, Strawberry, playlist or csv) or music folder", "")
 		fileName = filepath.Clean(fileName)
-		lib, err = itunes.ParseFile(fileName)
+		lib, err = ReadLibrary(program, fileName)
 		if nil == err {
 			break
 		}
 		program.Error(err.Error())
 	}
 
-	program.Log("Library file read successfully!")
+	program.Log("Library read successfully!")
 	program.Log(lib.String())
 
+	activeProfile, err = LoadMatchProfile(lib.LibraryFile)
+	if nil != err {
+		program.Warningf("using the default match profile: %s", err)
+	} else if activeProfile != defaultProfile {
+		program.Logf("using the adopted match profile: %s", activeProfile)
+	}
+	activeAliases, err = LoadAliases(lib.LibraryFile)
+	if nil != err {
+		program.Warningf("ignoring aliases: %s", err)
+	}
+
 	if !Session.IsAuthenticated() {
-		program.Warning("You are not logged Spotify, import cannot continue")
+		program.Warningf("You are not logged Spotify, %s cannot continue", cmd.Name)
 		os.Exit(1)
 	}
 
 	////////////
-	// hand off to importer
+	// hand off to the selected command
 	////////////
-	importer := NewImporter(program, lib)
-	importer.Run()
+	cmd.Run(program, lib)
 
 	Session.Logout()
 	os.Exit(0)
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// MatchProfile holds the weights that TrackCompare gives to each
+// part of a comparison, which can be learned from manual decisions
+type MatchProfile struct {
+	TitleWeight        float64
+	ArtistWeight       float64
+	AlbumWeight        float64
+	PopularityWeight   float64
+	CompilationPenalty float64
+
+	// penalties for version mismatches by name, see defaultVersionPenalties
+	VersionPenalties map[string]float64 `json:",omitempty"`
+
+	// how the profile was learned, if it was
+	Learned   time.Time `json:",omitempty"`
+	Decisions int       `json:",omitempty"`
+	Accuracy  float64   `json:",omitempty"`
+}
+
+// defaultProfile is the hand tuned profile
+var defaultProfile = &MatchProfile{
+	TitleWeight:        titleWeight,
+	ArtistWeight:       artistWeight,
+	AlbumWeight:        albumWeight,
+	PopularityWeight:   popularityWeight,
+	CompilationPenalty: compilationPenalty,
+}
+
+// activeProfile is the profile used by TrackCompare
+var activeProfile = defaultProfile
+
+// minNameWeight is the smallest weight that a learned profile gives to
+// the title, artist and album, so that none of them can be switched off
+const minNameWeight = 0.01
+
+// weights returns the profile weights in the order of ScoreBreakdown.Features
+func (mp *MatchProfile) weights() []float64 {
+	return []float64{
+		mp.TitleWeight,
+		mp.ArtistWeight,
+		mp.AlbumWeight,
+		mp.PopularityWeight,
+		mp.CompilationPenalty,
+	}
+}
+
+func (mp *MatchProfile) String() string {
+	return fmt.Sprintf("title %.3f, artist %.3f, album %.3f, popularity %.3f, compilation %.3f",
+		mp.TitleWeight, mp.ArtistWeight, mp.AlbumWeight, mp.PopularityWeight, mp.CompilationPenalty)
+}
+
+// LoadMatchProfile loads the adopted profile for the given library,
+// returning the default profile if there is none or it cannot be read
+func LoadMatchProfile(libraryPath string) (*MatchProfile, error) {
+
+	jsonData, err := ioutil.ReadFile(itspFile(libraryPath, "profile"))
+	if os.IsNotExist(err) {
+		return defaultProfile, nil
+	} else if nil != err {
+		return defaultProfile, err
+	}
+	profile := &MatchProfile{}
+	if err = json.Unmarshal(jsonData, profile); nil != err {
+		return defaultProfile, fmt.Errorf("error reading match profile: %s", err)
+	}
+	return profile, nil
+
+}
+
+// SaveMatchProfile writes the given profile to a file
+func SaveMatchProfile(fileName string, profile *MatchProfile) error {
+
+	jsonData, err := json.MarshalIndent(profile, "", "  ")
+	if nil != err {
+		return err
+	}
+	return ioutil.WriteFile(fileName, jsonData, 0644)
+
+}
+
+// Decision is a manual choice between candidates, kept as labeled
+// examples for learning a match profile
+type Decision struct {
+	ItunesTrack string
+	ChosenID    string
+	Candidates  []DecisionCandidate
+}
+
+// DecisionCandidate is one of the candidates in a decision
+type DecisionCandidate struct {
+	SpotifyID string
+	Features  []float64
+	Chosen    bool
+}
+
+// DecisionLog appends decisions to a json lines file
+// kept next to the library
+type DecisionLog struct {
+	LogFile string
+}
+
+// InitDecisionLog creates the decision log for the given library file
+func InitDecisionLog(libraryPath string) *DecisionLog {
+	return &DecisionLog{LogFile: itspFile(libraryPath, "decisions")}
+}
+
+// Log records the choice of chosen (or none if nil) between candidates
+func (dl *DecisionLog) Log(goal *itunes.Track, chosen *MatchedTrack, candidates []*MatchedTrack) error {
+
+	decision := Decision{ItunesTrack: ItunesCacheString(goal)}
+	if nil != chosen && chosen.Valid() {
+		decision.ChosenID = chosen.spotify.ID.String()
+	}
+
+	// only candidates scored with the match settings are
+	// comparable with the scores that the profile is used for
+	for _, mt := range candidates {
+		if !mt.Valid() || nil == mt.breakdown || nil == mt.breakdown.Features() {
+			continue
+		}
+		id := mt.spotify.ID.String()
+		decision.Candidates = append(decision.Candidates, DecisionCandidate{
+			SpotifyID: id,
+			Features:  mt.breakdown.Features(),
+			Chosen:    id == decision.ChosenID,
+		})
+	}
+	if len(decision.Candidates) == 0 {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(decision)
+	if nil != err {
+		return err
+	}
+	f, err := os.OpenFile(dl.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(jsonData, '\n'))
+	return err
+
+}
+
+// ReadDecisions reads all of the decisions in this log
+func (dl *DecisionLog) ReadDecisions() ([]Decision, error) {
+
+	f, err := os.Open(dl.LogFile)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	var decisions []Decision
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var d Decision
+		if err = json.Unmarshal(scanner.Bytes(), &d); nil != err {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, scanner.Err()
+
+}
+
+// FitMatchProfile fits profile weights to the given decisions with a
+// logistic regression, where a lower weighted score should mean a higher
+// chance of being chosen. The weights are scaled so that the comparison
+// weights add up to one like the default profile, keeping the match
+// thresholds meaningful
+func FitMatchProfile(decisions []Decision, start *MatchProfile) *MatchProfile {
+
+	var xs [][]float64
+	var ys []float64
+	positives := 0
+	for _, d := range decisions {
+		for _, c := range d.Candidates {
+			if len(c.Features) != len(start.weights()) {
+				continue
+			}
+			xs = append(xs, c.Features)
+			if c.Chosen {
+				ys = append(ys, 1)
+				positives++
+			} else {
+				ys = append(ys, 0)
+			}
+		}
+	}
+	if positives == 0 || positives == len(xs) {
+		return start
+	}
+
+	// chosen candidates are rare, so they count for more
+	posWeight := float64(len(xs)-positives) / float64(positives)
+
+	w := start.weights()
+	bias := 0.0
+	const (
+		rate       = 0.5
+		iterations = 5000
+		lambda     = 0.001
+	)
+	for it := 0; it < iterations; it++ {
+
+		grad := make([]float64, len(w))
+		gradBias := 0.0
+		total := 0.0
+		for j, x := range xs {
+			z := bias
+			for k := range w {
+				z -= w[k] * x[k]
+			}
+			p := 1.0 / (1.0 + math.Exp(-z))
+			sampleWeight := 1.0
+			if ys[j] == 1 {
+				sampleWeight = posWeight
+			}
+			diff := sampleWeight * (p - ys[j])
+			for k := range w {
+				grad[k] -= diff * x[k]
+			}
+			gradBias += diff
+			total += sampleWeight
+		}
+
+		for k := range w {
+			w[k] -= rate * (grad[k]/total + lambda*w[k])
+			// a better comparison should never make a match less likely
+			if w[k] < 0 {
+				w[k] = 0
+			}
+		}
+		// the names are never ignored, so that their rule
+		// rejections still add up to the whole score
+		for k := 0; k < 3; k++ {
+			w[k] = math.Max(w[k], minNameWeight)
+		}
+		bias -= rate * gradBias / total
+
+	}
+
+	sum := w[0] + w[1] + w[2] + w[3]
+	if sum <= 0 {
+		return start
+	}
+	for k := range w {
+		w[k] /= sum
+	}
+
+	profile := &MatchProfile{
+		TitleWeight:        w[0],
+		ArtistWeight:       w[1],
+		AlbumWeight:        w[2],
+		PopularityWeight:   w[3],
+		CompilationPenalty: w[4],
+		VersionPenalties:   start.VersionPenalties,
+		Learned:            time.Now(),
+		Decisions:          len(decisions),
+	}
+	profile.Accuracy = profile.accuracy(decisions)
+	return profile
+
+}
+
+// accuracy is the fraction of decisions with a chosen candidate
+// where that candidate has the best score with this profile
+func (mp *MatchProfile) accuracy(decisions []Decision) float64 {
+
+	w := mp.weights()
+	correct, total := 0, 0
+	for _, d := range decisions {
+
+		if d.ChosenID == "" {
+			continue
+		}
+		best, bestScore := "", math.Inf(1)
+		for _, c := range d.Candidates {
+			if len(c.Features) != len(w) {
+				continue
+			}
+			score := 0.0
+			for k := range w {
+				score += w[k] * c.Features[k]
+			}
+			if score < bestScore {
+				best, bestScore = c.SpotifyID, score
+			}
+		}
+		total++
+		if best == d.ChosenID {
+			correct++
+		}
+
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(correct) / float64(total)
+
+}
+
+// Learner fits a match profile to the decisions logged for a library
+type Learner struct {
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// NewLearner creates a new learner for the command program and library
+func NewLearner(program *SimpleCommandProgram, lib *Library) *Learner {
+	return &Learner{lib: lib, program: program}
+}
+
+// Run fits a profile, writes it out for review and offers to adopt it
+func (l *Learner) Run() {
+
+	decisions, err := InitDecisionLog(l.lib.LibraryFile).ReadDecisions()
+	if nil != err {
+		l.program.Errorf("Error reading decisions: %s", err)
+		return
+	}
+	l.program.Logf("learning from %d decisions...", len(decisions))
+	if len(decisions) < 20 {
+		l.program.Warning("there are very few decisions, the profile may not be reliable")
+	}
+
+	current, err := LoadMatchProfile(l.lib.LibraryFile)
+	if nil != err {
+		l.program.Warningf("starting from the default profile: %s", err)
+	}
+	learned := FitMatchProfile(decisions, current)
+	if learned == current {
+		l.program.Error("the decisions need both chosen and rejected candidates to learn from")
+		return
+	}
+
+	l.program.Logf("current: %s (%.0f%% accurate)", current, 100*current.accuracy(decisions))
+	l.program.Logf("learned: %s (%.0f%% accurate)", learned, 100*learned.Accuracy)
+
+	learnedFile := itspFile(l.lib.LibraryFile, "profile.learned")
+	if err = SaveMatchProfile(learnedFile, learned); nil != err {
+		l.program.Errorf("Error writing profile: %s", err)
+		return
+	}
+	l.program.Logf("learned profile written to %s", learnedFile)
+
+	if l.program.AskYesNo("Adopt the learned profile for this library?", false) {
+		profileFile := itspFile(l.lib.LibraryFile, "profile")
+		if err = SaveMatchProfile(profileFile, learned); nil != err {
+			l.program.Errorf("Error writing profile: %s", err)
+			return
+		}
+		l.program.Logf("adopted, remove %s to go back to the default profile", profileFile)
+	}
+
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// testDecisions builds decisions where the chosen candidate always has
+// the better album but the worse popularity of the two candidates
+func testDecisions(n int) []Decision {
+
+	var decisions []Decision
+	for j := 0; j < n; j++ {
+		chosen := fmt.Sprintf("chosen%d", j)
+		decisions = append(decisions, Decision{
+			ItunesTrack: fmt.Sprintf("track %d", j),
+			ChosenID:    chosen,
+			Candidates: []DecisionCandidate{
+				{SpotifyID: fmt.Sprintf("other%d", j), Features: []float64{0.05, 0, 0.5, 0, 0}},
+				{SpotifyID: chosen, Features: []float64{0.05, 0, 0, 0.9, 0}, Chosen: true},
+			},
+		})
+	}
+	// a decision without a choice does not count towards accuracy
+	decisions = append(decisions, Decision{
+		ItunesTrack: "unavailable",
+		Candidates:  []DecisionCandidate{{SpotifyID: "any", Features: []float64{0.5, 0.5, 0.5, 0.5, 0}}},
+	})
+	return decisions
+
+}
+
+func TestFitMatchProfile(t *testing.T) {
+
+	decisions := testDecisions(20)
+	if accuracy := defaultProfile.accuracy(decisions); accuracy != 0 {
+		t.Errorf("expected the default profile to prefer popularity, got %.2f accuracy", accuracy)
+	}
+
+	learned := FitMatchProfile(decisions, defaultProfile)
+	if learned == defaultProfile {
+		t.Fatal("expected a profile to be learned")
+	}
+	if learned.AlbumWeight/learned.PopularityWeight <= albumWeight/popularityWeight {
+		t.Errorf("expected the album to gain on popularity, got %s", learned)
+	}
+	if learned.Accuracy != 1 {
+		t.Errorf("expected the learned profile to pick every choice, got %.2f accuracy", learned.Accuracy)
+	}
+	if learned.Decisions != len(decisions) {
+		t.Errorf("expected %d decisions, got %d", len(decisions), learned.Decisions)
+	}
+
+	sum := learned.TitleWeight + learned.ArtistWeight + learned.AlbumWeight + learned.PopularityWeight
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("expected the comparison weights to add up to one, got %f", sum)
+	}
+	// the artist never differs, so only the floor keeps it on
+	if learned.TitleWeight <= 0 || learned.ArtistWeight <= 0 || learned.AlbumWeight <= 0 {
+		t.Errorf("expected the name weights to stay above zero, got %s", learned)
+	}
+
+	// all chosen or all rejected cannot be learned from
+	onlyChosen := []Decision{{ChosenID: "a", Candidates: []DecisionCandidate{
+		{SpotifyID: "a", Features: []float64{0, 0, 0, 0, 0}, Chosen: true}}}}
+	if FitMatchProfile(onlyChosen, defaultProfile) != defaultProfile {
+		t.Errorf("expected the starting profile without rejected candidates")
+	}
+
+}
+
+func TestRuleRejection(t *testing.T) {
+
+	defer func() { activeProfile = defaultProfile }()
+
+	for _, profile := range []*MatchProfile{
+		defaultProfile,
+		{TitleWeight: 0.7, ArtistWeight: 0.01, AlbumWeight: 0.04, PopularityWeight: 0.25},
+	} {
+		activeProfile = profile
+
+		score, _, rule := albumCompare("the musical", "the musical cast recording", false, false)
+		if rule != "cast" || math.Abs(score*profile.AlbumWeight-1) > 1e-9 {
+			t.Errorf("expected the cast album to be rejected by %s, got %f (%s)", profile, score*profile.AlbumWeight, rule)
+		}
+		score, _, rule = artistCompare("the band", "karaoke stars", false)
+		if rule != "karaoke" || math.Abs(score*profile.ArtistWeight-1) > 1e-9 {
+			t.Errorf("expected the karaoke artist to be rejected by %s, got %f (%s)", profile, score*profile.ArtistWeight, rule)
+		}
+	}
+
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// LibraryFormat identifies the kind of source
+// that a library was read from
+type LibraryFormat string
+
+const (
+	// FormatITunes is the classic iTunes Library XML
+	FormatITunes LibraryFormat = "iTunes"
+	// FormatMusic is the XML exported by macOS Music.app
+	FormatMusic LibraryFormat = "Music.app"
+	// FormatM3U is an M3U or M3U8 playlist file
+	FormatM3U LibraryFormat = "M3U"
+	// FormatPLS is a PLS playlist file
+	FormatPLS LibraryFormat = "PLS"
+	// FormatXSPF is an XML shareable playlist file
+	FormatXSPF LibraryFormat = "XSPF"
+	// FormatFolder is a folder of tagged audio files
+	FormatFolder LibraryFormat = "Folder"
+	// FormatCSV is a csv or tsv track list
+	FormatCSV LibraryFormat = "CSV"
+	// FormatRhythmbox is a Rhythmbox rhythmdb.xml database
+	FormatRhythmbox LibraryFormat = "Rhythmbox"
+	// FormatStrawberry is a Strawberry sqlite database
+	FormatStrawberry LibraryFormat = "Strawberry"
+	// FormatClementine is a Clementine sqlite database
+	FormatClementine LibraryFormat = "Clementine"
+)
+
+// LibraryReader reads a music library or playlist source
+// into the library model that is consumed by the importer
+type LibraryReader interface {
+	// Detect returns true if this reader can read the given path
+	Detect(path string) bool
+	// Read reads the library found at the given path
+	Read(path string) (*Library, error)
+}
+
+// optionsAsker is implemented by the readers that have options
+// to ask the user, which are asked once the format is detected
+// and before the library is read
+type optionsAsker interface {
+	askOptions(program *SimpleCommandProgram, path string) error
+}
+
+// TrackExtras holds track information that is available from
+// some library formats but not carried by the itunes library model
+type TrackExtras struct {
+	AppleMusic bool
+	Matched    bool
+	Purchased  bool
+
+	ISRC                string
+	MusicBrainzTrackID  string
+	MusicBrainzAlbumID  string
+	MusicBrainzArtistID string
+
+	// classical metadata
+	Work           string
+	MovementName   string
+	MovementNumber int
+	Conductor      string
+
+	// content rating, where a track with neither is unrated
+	Explicit bool
+	Clean    bool
+}
+
+// StreamingOnly returns true if the track is only available through
+// an Apple Music subscription, with no local or matched copy
+func (te *TrackExtras) StreamingOnly() bool {
+	return te.AppleMusic && !te.Matched && !te.Purchased
+}
+
+// Library is a parsed itunes library along with the format that
+// it was read from and any extra information that came with it
+type Library struct {
+	*itunes.Library
+
+	Format LibraryFormat
+
+	// extras are stored by track persistent id
+	extras map[string]*TrackExtras
+	// distinguished kinds are stored by playlist persistent id
+	kinds map[string]int
+}
+
+// Extras returns the extra information for the given track,
+// which is empty if nothing extra was available
+func (l *Library) Extras(track *itunes.Track) *TrackExtras {
+	if te, ok := l.extras[track.PersistentID]; ok {
+		return te
+	}
+	return &TrackExtras{}
+}
+
+// DistinguishedKind returns the Music.app kind for the given
+// playlist, which is non-zero for all built in playlists
+func (l *Library) DistinguishedKind(playlist *itunes.Playlist) int {
+	return l.kinds[playlist.PlaylistPersistentID]
+}
+
+// ReadLibrary reads the library at the given path
+// using the first reader that recognizes its format
+func ReadLibrary(program *SimpleCommandProgram, path string) (*Library, error) {
+
+	if _, err := os.Stat(path); nil != err {
+		return nil, err
+	}
+
+	// readers are checked in order when detecting the format
+	readers := []LibraryReader{
+		&xmlLibraryReader{},
+		&rhythmboxReader{},
+		&strawberryReader{},
+		&playlistFileReader{},
+		&csvReader{},
+		&folderReader{program: program},
+	}
+
+	for _, reader := range readers {
+		if !reader.Detect(path) {
+			continue
+		}
+		if asker, ok := reader.(optionsAsker); ok {
+			if err := asker.askOptions(program, path); nil != err {
+				return nil, err
+			}
+		}
+		return reader.Read(path)
+	}
+
+	return nil, fmt.Errorf("unrecognized library format: %s", path)
+
+}
+
+// xmlLibraryReader reads the plist xml libraries exported
+// by iTunes and Music.app, as well as the compatible ones
+// written by other players such as MusicBee
+type xmlLibraryReader struct{}
+
+func (r *xmlLibraryReader) Detect(path string) bool {
+
+	if strings.ToLower(filepath.Ext(path)) != ".xml" {
+		return false
+	}
+	head, err := readFileHead(path, 512)
+	return nil == err && strings.Contains(head, "<plist")
+
+}
+
+func (r *xmlLibraryReader) Read(path string) (*Library, error) {
+
+	library, err := itunes.ParseFile(path)
+	if nil != err {
+		return nil, err
+	}
+
+	// the library model leaves out the keys added by Music.app,
+	// so they are read from the raw plist for the extras instead
+	raw, err := readPlistFile(path)
+	if nil != err {
+		return nil, err
+	}
+
+	lib := &Library{
+		Library: library,
+		Format:  DetectLibraryFormat(raw),
+		extras:  make(map[string]*TrackExtras),
+		kinds:   make(map[string]int),
+	}
+
+	for _, value := range raw.Dict("Tracks") {
+		track, ok := value.(plistDict)
+		if !ok {
+			continue
+		}
+		lib.extras[track.String("Persistent ID")] = &TrackExtras{
+			AppleMusic: track.Bool("Apple Music"),
+			Matched:    track.Bool("Matched"),
+			Purchased:  track.Bool("Purchased"),
+
+			Work:           track.String("Work"),
+			MovementName:   track.String("Movement Name"),
+			MovementNumber: track.Int("Movement Number"),
+
+			Explicit: track.Bool("Explicit"),
+			Clean:    track.Bool("Clean"),
+		}
+	}
+
+	for _, value := range raw.Array("Playlists") {
+		playlist, ok := value.(plistDict)
+		if !ok {
+			continue
+		}
+		if kind := playlist.Int("Distinguished Kind"); kind != 0 {
+			lib.kinds[playlist.String("Playlist Persistent ID")] = kind
+		}
+	}
+
+	return lib, nil
+
+}
+
+// DetectLibraryFormat decides which application produced the given
+// library plist, based on its version and the keys that it uses
+func DetectLibraryFormat(raw plistDict) LibraryFormat {
+
+	// MusicBee writes plain iTunes xml, but with its own version
+	// number which could otherwise be mistaken for Music.app
+	if strings.Contains(raw.String("Application Version"), "MusicBee") {
+		return FormatITunes
+	}
+
+	// Music.app restarted its version numbering at 1.0,
+	// where iTunes versions used in xml exports are all 7+
+	version := raw.String("Application Version")
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if nil == err && major < 7 {
+		return FormatMusic
+	}
+
+	for _, value := range raw.Dict("Tracks") {
+		track, ok := value.(plistDict)
+		if !ok {
+			continue
+		}
+		if _, ok := track["Apple Music"]; ok {
+			return FormatMusic
+		}
+		if _, ok := track["Playlist Only"]; ok {
+			return FormatMusic
+		}
+	}
+
+	return FormatITunes
+
+}
+
+// libraryBuilder assembles a library from sources that do
+// not have their own itunes track ids and persistent ids
+type libraryBuilder struct {
+	lib   *Library
+	byKey map[string]*itunes.Track
+}
+
+func newLibraryBuilder(path string, format LibraryFormat) *libraryBuilder {
+
+	return &libraryBuilder{
+		lib: &Library{
+			Library: &itunes.Library{
+				LibraryFile: path,
+				TracksByID:  make(map[int]*itunes.Track),
+			},
+			Format: format,
+			extras: make(map[string]*TrackExtras),
+			kinds:  make(map[string]int),
+		},
+		byKey: make(map[string]*itunes.Track),
+	}
+
+}
+
+// AddTrack adds the given track and its extras (which can be nil) to
+// the library, returning the already added instance instead if the
+// same track was seen before
+func (b *libraryBuilder) AddTrack(track *itunes.Track, extras *TrackExtras) *itunes.Track {
+
+	key := strings.ToLower(track.Location)
+	if key == "" {
+		key = strings.ToLower(fmt.Sprintf("%s|%s|%s", track.Name, track.Artist, track.Album))
+	}
+	if existing, ok := b.byKey[key]; ok {
+		return existing
+	}
+
+	// persistent ids need to be stable between runs
+	// so that previous matches can be found in the cache
+	track.TrackID = len(b.lib.Tracks) + 1
+	track.PersistentID = strings.ToUpper(fmt.Sprintf("%x", sha1.Sum([]byte(key)))[:16])
+
+	b.byKey[key] = track
+	b.lib.Tracks = append(b.lib.Tracks, track)
+	b.lib.TracksByID[track.TrackID] = track
+	if nil != extras {
+		b.lib.extras[track.PersistentID] = extras
+	}
+	return track
+
+}
+
+// AddPlaylist adds a playlist of previously added tracks to the library
+func (b *libraryBuilder) AddPlaylist(name string, tracks []*itunes.Track) {
+
+	b.lib.Playlists = append(b.lib.Playlists, &itunes.Playlist{
+		Name:          name,
+		PlaylistItems: tracks,
+	})
+
+}
+
+// readFileHead reads up to n bytes from the start of the file at path
+func readFileHead(path string, n int) (string, error) {
+
+	f, err := os.Open(path)
+	if nil != err {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if nil != err && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return string(buf[:read]), nil
+
+}
+
+// String returns a short description of this library and its source
+func (l *Library) String() string {
+	return fmt.Sprintf("[%s] %s", l.Format, l.Library.String())
+}
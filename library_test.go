@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testLibraryXML = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Application Version</key><string>1.1</string>
+	<key>Tracks</key>
+	<dict>
+		<key>5</key>
+		<dict>
+			<key>Track ID</key><integer>5</integer>
+			<key>Name</key><string>First Song</string>
+			<key>Artist</key><string>The Band</string>
+			<key>Persistent ID</key><string>AAAA</string>
+			<key>Date Added</key><date>2020-01-02T03:04:05Z</date>
+			<key>Explicit</key><true/>
+		</dict>
+	</dict>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Name</key><string>Music</string>
+			<key>Playlist Persistent ID</key><string>0001</string>
+			<key>Distinguished Kind</key><integer>4</integer>
+			<key>Playlist Items</key><array><dict><key>Track ID</key><integer>5</integer></dict></array>
+		</dict>
+		<dict>
+			<key>Name</key><string>Music</string>
+			<key>Playlist Persistent ID</key><string>0002</string>
+			<key>Playlist Items</key><array><dict><key>Track ID</key><integer>5</integer></dict></array>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+func TestReadXMLLibrary(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "Library.xml")
+	if err = ioutil.WriteFile(path, []byte(testLibraryXML), 0644); nil != err {
+		t.Fatal(err)
+	}
+
+	reader := &xmlLibraryReader{}
+	if !reader.Detect(path) {
+		t.Fatal("expected the xml library to be detected")
+	}
+	lib, err := reader.Read(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if len(lib.Tracks) != 1 || lib.TracksByID[5].Name != "First Song" || lib.Tracks[0].DateAdded.Year() != 2020 {
+		t.Errorf("expected the track to be read, got %+v", lib.Tracks)
+	}
+	if !lib.Extras(lib.Tracks[0]).Explicit {
+		t.Error("expected the extras to be read from the same plist")
+	}
+	if len(lib.Playlists) != 2 || len(lib.Playlists[1].PlaylistItems) != 1 {
+		t.Fatalf("expected both playlists with their tracks, got %d", len(lib.Playlists))
+	}
+	if lib.DistinguishedKind(lib.Playlists[0]) != 4 || lib.DistinguishedKind(lib.Playlists[1]) != 0 {
+		t.Error("a user playlist with the name of a built in one should not be distinguished")
+	}
+
+}
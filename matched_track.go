@@ -12,7 +12,8 @@ type MatchedTrack struct {
 	spotify *spotify.FullTrack
 	sAlbum  *spotify.FullAlbum
 
-	score float64
+	score     float64
+	breakdown *ScoreBreakdown
 }
 
 // FullAlbum returns the full album for this matches spotify track,
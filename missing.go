@@ -5,27 +5,30 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 
 	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
 )
 
 // MissingLog is a log to hold missing entries and where they belong
 type MissingLog struct {
 	LogFile string
 	Entries map[string][]string
+
+	// entries that were matched but have a problem, eg: they
+	// cannot be played in the user's market
+	FlagFile string
+	Flags    map[string][]string
 }
 
 // InitMissingLog starts a new missing log for outputting at the end of the session
 func InitMissingLog(itunesLibraryPath string) *MissingLog {
 
-	ext := path.Ext(itunesLibraryPath)
-	baseName := itunesLibraryPath[0 : len(itunesLibraryPath)-len(ext)]
-	logFile := fmt.Sprintf("%s.itsp.missing", baseName)
-
 	log := &MissingLog{
-		LogFile: logFile,
-		Entries: make(map[string][]string),
+		LogFile:  itspFile(itunesLibraryPath, "missing"),
+		Entries:  make(map[string][]string),
+		FlagFile: itspFile(itunesLibraryPath, "flags"),
+		Flags:    make(map[string][]string),
 	}
 
 	return log
@@ -42,7 +45,24 @@ func (ml *MissingLog) SaveLog() error {
 		return err
 	}
 
-	return ioutil.WriteFile(ml.LogFile, jsonData, os.ModeExclusive)
+	if err = ioutil.WriteFile(ml.LogFile, jsonData, os.ModeExclusive); nil != err {
+		return err
+	}
+
+	// flags from an earlier run are removed once they are resolved
+	if len(ml.Flags) == 0 {
+		if err = os.Remove(ml.FlagFile); nil != err && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	jsonData, err = json.Marshal(ml.Flags)
+	if nil != err {
+		fmt.Printf("error marshalling flags: %s", err)
+		return err
+	}
+
+	return ioutil.WriteFile(ml.FlagFile, jsonData, os.ModeExclusive)
 
 }
 
@@ -56,3 +76,22 @@ func (ml *MissingLog) Log(destination string, track *itunes.Track) {
 	ml.Entries[destination] = append(ml.Entries[destination], ItunesCacheString(track))
 
 }
+
+// Flag logs the given track as matched with a problem
+func (ml *MissingLog) Flag(destination string, track *itunes.Track, reason string) {
+
+	ml.Flags[destination] = append(ml.Flags[destination],
+		fmt.Sprintf("%s: %s", ItunesCacheString(track), reason))
+
+}
+
+// LogSpotify logs the given spotify track in this map
+func (ml *MissingLog) LogSpotify(destination string, track *spotify.FullTrack) {
+
+	if _, ok := ml.Entries[destination]; !ok {
+		ml.Entries[destination] = make([]string, 0)
+	}
+
+	ml.Entries[destination] = append(ml.Entries[destination], SpotifyCacheString(track))
+
+}
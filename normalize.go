@@ -0,0 +1,238 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// accentFolder removes the combining marks left by decomposition
+var accentFolder = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// foldedLetters are letters that do not decompose
+// into a base letter and accent, but should still fold
+var foldedLetters = strings.NewReplacer(
+	"ø", "o", "Ø", "O",
+	"æ", "ae", "Æ", "AE",
+	"œ", "oe", "Œ", "OE",
+	"ß", "ss",
+	"đ", "d", "Đ", "D",
+	"ł", "l", "Ł", "L",
+	"þ", "th", "Þ", "TH",
+	"ð", "d", "Ð", "D",
+	"ı", "i",
+)
+
+// punctuationReplacer makes the many forms of quotes,
+// dashes and ellipses into their plain ascii forms
+var punctuationReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'", "`", "'", "´", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`, "«", `"`, "»", `"`,
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+	"…", "...",
+)
+
+// normalizeText applies compatibility normalization, which also makes
+// full width characters into their usual forms, and plain punctuation
+func normalizeText(s string) string {
+	return punctuationReplacer.Replace(norm.NFKC.String(s))
+}
+
+// normalizeName prepares a name for comparison, normalizing the
+// text, folding accents and case and trimming the surrounding space
+func normalizeName(s string) string {
+
+	s = normalizeText(s)
+	if folded, _, err := transform.String(accentFolder, s); nil == err {
+		s = folded
+	}
+	s = foldedLetters.Replace(s)
+	return strings.TrimSpace(strings.ToLower(s))
+
+}
+
+// compareName normalizes a name for comparison, first transliterating
+// cyrillic, greek and japanese kana into latin letters when asked, so
+// that a library in one script can match a catalogue in another
+func compareName(s string, transliterateNames bool) string {
+
+	// transliterate before folding, which would
+	// otherwise turn letters like й into и
+	if transliterateNames {
+		s = transliterate(normalizeText(s))
+	}
+	return normalizeName(s)
+
+}
+
+// transliterate replaces cyrillic, greek and kana letters with latin ones
+func transliterate(s string) string {
+
+	rs := []rune(s)
+	var b strings.Builder
+	for j := 0; j < len(rs); j++ {
+
+		r := rs[j]
+
+		// kana combine with a following small ya, yu or yo
+		if j+1 < len(rs) {
+			if latin, ok := kanaDigraphs[string(rs[j:j+2])]; ok {
+				b.WriteString(latin)
+				j++
+				continue
+			}
+		}
+
+		// a small tsu doubles the next consonant
+		if (r == 'っ' || r == 'ッ') && j+1 < len(rs) {
+			if next := transliterateRune(rs[j+1]); next != "" && !strings.ContainsAny(next[:1], "aeiou") {
+				b.WriteString(next[:1])
+				continue
+			}
+		}
+
+		// a long vowel mark repeats the previous vowel
+		if r == 'ー' {
+			str := b.String()
+			if len(str) > 0 && strings.ContainsAny(str[len(str)-1:], "aeiou") {
+				b.WriteString(str[len(str)-1:])
+			}
+			continue
+		}
+
+		if latin := transliterateRune(r); latin != "" {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+
+	}
+	return b.String()
+
+}
+
+func transliterateRune(r rune) string {
+
+	lower := unicode.ToLower(r)
+	upper := lower != r
+	if latin, ok := cyrillicLetters[lower]; ok {
+		if upper && latin != "" {
+			return strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		return latin
+	}
+	// greek letters are looked up without their accents
+	if base := []rune(norm.NFD.String(string(lower))); len(base) > 0 {
+		lower = base[0]
+	}
+	if latin, ok := greekLetters[lower]; ok {
+		if upper {
+			return strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		return latin
+	}
+	// katakana are read like the hiragana of the same sound
+	if r >= 'ァ' && r <= 'ヶ' {
+		r -= 'ァ' - 'ぁ'
+	}
+	return kanaLetters[r]
+
+}
+
+var cyrillicLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ђ': "dj",
+}
+
+var greekLetters = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+var kanaLetters = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo", 'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+}
+
+// kanaDigraphs are the sounds written with a small ya, yu or yo
+var kanaDigraphs = map[string]string{}
+
+func init() {
+
+	for _, first := range []struct {
+		kana  rune
+		latin string
+	}{
+		{'き', "ky"}, {'ぎ', "gy"}, {'し', "sh"}, {'じ', "j"}, {'ち', "ch"},
+		{'に', "ny"}, {'ひ', "hy"}, {'び', "by"}, {'ぴ', "py"}, {'み', "my"},
+		{'り', "ry"},
+	} {
+		for _, second := range []struct {
+			kana  rune
+			latin string
+		}{{'ゃ', "a"}, {'ゅ', "u"}, {'ょ', "o"}} {
+			latin := first.latin + second.latin
+			kanaDigraphs[string([]rune{first.kana, second.kana})] = latin
+			katakana := []rune{
+				first.kana + ('ァ' - 'ぁ'),
+				second.kana + ('ァ' - 'ぁ'),
+			}
+			kanaDigraphs[string(katakana)] = latin
+		}
+	}
+
+}
+
+// wagnerFischer is the edit distance between a and b, counting runes
+// rather than bytes so that multibyte letters cost the same as any other
+func wagnerFischer(a, b []rune, icost, dcost, scost int) int {
+
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j * icost
+	}
+
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i * dcost
+		for j := 1; j <= len(b); j++ {
+			cost := prev
+			if a[i-1] != b[j-1] {
+				cost += scost
+			}
+			if ins := row[j-1] + icost; ins < cost {
+				cost = ins
+			}
+			if del := row[j] + dcost; del < cost {
+				cost = del
+			}
+			prev, row[j] = row[j], cost
+		}
+	}
+	return row[len(b)]
+
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+func TestNormalizeName(t *testing.T) {
+
+	for _, c := range [][2]string{
+		{"Sigur Rós", "sigur ros"},
+		{"Beyoncé", "beyonce"},
+		{"Ｆｕｌｌ Ｗｉｄｔｈ", "full width"},
+		{"Don’t Stop “Believin’”", `don't stop "believin'"`},
+		{"Wait — What…", "wait - what..."},
+		{"Mø", "mo"},
+		{"  Motörhead ", "motorhead"},
+	} {
+		if got := normalizeName(c[0]); got != c[1] {
+			t.Errorf("expected %q to normalize to %q, got %q", c[0], c[1], got)
+		}
+	}
+
+}
+
+func TestTransliterate(t *testing.T) {
+
+	for _, c := range [][2]string{
+		{"Кино", "kino"},
+		{"Земфира", "zemfira"},
+		{"Βαγγέλης", "vaggelis"},
+		{"さくら", "sakura"},
+		{"トーキョー", "tookyoo"},
+		{"ちょっと", "chotto"},
+	} {
+		if got := compareName(c[0], true); got != c[1] {
+			t.Errorf("expected %q to transliterate to %q, got %q", c[0], c[1], got)
+		}
+	}
+
+}
+
+func TestCompareTransliterated(t *testing.T) {
+
+	goal := &itunes.Track{Name: "Группа крови", Artist: "Кино"}
+	test := &spotify.FullTrack{}
+	test.Name = "Gruppa Krovi"
+	test.Artists = []spotify.SimpleArtist{{Name: "Kino"}}
+
+	plain := CompareTracks(goal, test, CompareOptions{IgnoreAlbum: true})
+	transliterated := CompareTracks(goal, test, CompareOptions{IgnoreAlbum: true, Transliterate: true})
+	if transliterated.Title != 0 || transliterated.Artist != 0 || plain.Title == 0 {
+		t.Errorf("expected only the transliterated comparison to match, got %s and %s", plain, transliterated)
+	}
+
+}
+
+func TestUnicodeCompare(t *testing.T) {
+
+	if score := ArtistCompare("Sigur Ros", "Sigur Rós"); score != 0 {
+		t.Errorf("accents should not affect artist comparisons %f", score)
+	}
+
+	if score := TitleCompare("Ｈｅｌｌｏ", "hello"); score != 0 {
+		t.Errorf("full width characters should not affect title comparisons %f", score)
+	}
+
+	// a single differing letter should cost the same
+	// whether or not it takes more than one byte
+	ascii := wagnerFischerRelative("abcd", "abce", 1, 1, 1)
+	multibyte := wagnerFischerRelative("жжжж", "жжжа", 1, 1, 1)
+	if ascii != multibyte {
+		t.Errorf("multibyte letters should be counted as one %f != %f", ascii, multibyte)
+	}
+
+}
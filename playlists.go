@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// playlistEntry is a single entry read from a playlist file,
+// which may only be partially filled depending on the format
+type playlistEntry struct {
+	Location string
+	Title    string
+	Artist   string
+	Album    string
+	Duration int // milliseconds
+}
+
+// playlistFileReader reads M3U/M3U8, PLS and XSPF playlist files as
+// a library of one playlist, filling in track information from the
+// tags of any referenced local files
+type playlistFileReader struct{}
+
+func (r *playlistFileReader) Detect(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8", ".pls", ".xspf":
+		return true
+	}
+	return false
+}
+
+func (r *playlistFileReader) Read(path string) (*Library, error) {
+
+	f, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var entries []*playlistEntry
+	var format LibraryFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pls":
+		format = FormatPLS
+		entries, err = parsePLS(f)
+	case ".xspf":
+		format = FormatXSPF
+		var title string
+		title, entries, err = parseXSPF(f)
+		if title != "" {
+			name = title
+		}
+	default:
+		format = FormatM3U
+		entries, err = parseM3U(f)
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	builder := newLibraryBuilder(path, format)
+	var tracks []*itunes.Track
+	for _, entry := range entries {
+		track, extras := entry.Track(filepath.Dir(path))
+		tracks = append(tracks, builder.AddTrack(track, extras))
+	}
+	builder.AddPlaylist(name, tracks)
+
+	return builder.lib, nil
+
+}
+
+// Track creates an itunes track for this entry, resolving relative
+// locations against dir and reading tags from the file if it exists
+func (pe *playlistEntry) Track(dir string) (*itunes.Track, *TrackExtras) {
+
+	track := &itunes.Track{
+		Name:      pe.Title,
+		Artist:    pe.Artist,
+		Album:     pe.Album,
+		TotalTime: pe.Duration,
+	}
+
+	location := fileURLToPath(pe.Location)
+	if strings.Contains(location, "://") {
+		// streams and other remote entries only have what the playlist says
+		return track, nil
+	}
+	if location != "" && !filepath.IsAbs(location) {
+		location = filepath.Join(dir, location)
+	}
+
+	var extras *TrackExtras
+	if _, err := os.Stat(location); location != "" && nil == err {
+		track.Location = location
+		if tags, err := ReadFileTags(location); nil == err {
+			tags.Apply(track)
+			extras = tags.Extras()
+		}
+	}
+
+	// fall back to the file name, which is commonly "Artist - Title"
+	if track.Name == "" && location != "" {
+		base := filepath.Base(location)
+		track.Name, track.Artist = splitArtistTitle(
+			strings.TrimSuffix(base, filepath.Ext(base)), track.Artist)
+	}
+
+	return track, extras
+
+}
+
+// splitArtistTitle splits a display string of the form "Artist - Title",
+// returning the whole string as the title if there is no artist part
+func splitArtistTitle(s, defaultArtist string) (title, artist string) {
+	parts := strings.SplitN(s, " - ", 2)
+	if len(parts) == 2 && defaultArtist == "" {
+		return strings.TrimSpace(parts[1]), strings.TrimSpace(parts[0])
+	}
+	return strings.TrimSpace(s), defaultArtist
+}
+
+// playlistLine normalizes one line of a playlist file, converting
+// latin-1 text (common in plain .m3u files) into utf-8
+func playlistLine(line string) string {
+	if !utf8.ValidString(line) {
+		line = latin1String([]byte(line))
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+}
+
+func parseM3U(r io.Reader) ([]*playlistEntry, error) {
+
+	var entries []*playlistEntry
+	next := &playlistEntry{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+
+		line := playlistLine(scanner.Text())
+		switch {
+
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			// attributes may follow the duration, ie: #EXTINF:123 tvg-id="x",Title
+			if fields := strings.Fields(info[0]); len(fields) > 0 {
+				if secs, err := strconv.Atoi(fields[0]); nil == err && secs > 0 {
+					next.Duration = secs * 1000
+				}
+			}
+			if len(info) == 2 {
+				next.Title, next.Artist = splitArtistTitle(info[1], next.Artist)
+			}
+
+		case strings.HasPrefix(line, "#EXTART:"):
+			next.Artist = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+
+		case strings.HasPrefix(line, "#EXTALB:"):
+			next.Album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+
+		case strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			next.Location = line
+			entries = append(entries, next)
+			next = &playlistEntry{}
+
+		}
+
+	}
+
+	return entries, scanner.Err()
+
+}
+
+func parsePLS(r io.Reader) ([]*playlistEntry, error) {
+
+	byIndex := make(map[int]*playlistEntry)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+
+		parts := strings.SplitN(playlistLine(scanner.Text()), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		var field string
+		for _, f := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(key, f) {
+				field = f
+				break
+			}
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if field == "" || nil != err {
+			continue
+		}
+
+		entry, ok := byIndex[index]
+		if !ok {
+			entry = &playlistEntry{}
+			byIndex[index] = entry
+		}
+
+		switch field {
+		case "file":
+			entry.Location = value
+		case "title":
+			entry.Title, entry.Artist = splitArtistTitle(value, "")
+		case "length":
+			if secs, err := strconv.Atoi(value); nil == err && secs > 0 {
+				entry.Duration = secs * 1000
+			}
+		}
+
+	}
+
+	var indices []int
+	for index := range byIndex {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	var entries []*playlistEntry
+	for _, index := range indices {
+		entries = append(entries, byIndex[index])
+	}
+
+	return entries, scanner.Err()
+
+}
+
+// xspfPlaylist is the subset of the XSPF format that is used
+type xspfPlaylist struct {
+	Title  string `xml:"title"`
+	Tracks []struct {
+		Location []string `xml:"location"`
+		Title    string   `xml:"title"`
+		Creator  string   `xml:"creator"`
+		Album    string   `xml:"album"`
+		Duration int      `xml:"duration"`
+	} `xml:"trackList>track"`
+}
+
+func parseXSPF(r io.Reader) (string, []*playlistEntry, error) {
+
+	var doc xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&doc); nil != err {
+		return "", nil, err
+	}
+
+	var entries []*playlistEntry
+	for _, t := range doc.Tracks {
+		entry := &playlistEntry{
+			Title:    strings.TrimSpace(t.Title),
+			Artist:   strings.TrimSpace(t.Creator),
+			Album:    strings.TrimSpace(t.Album),
+			Duration: t.Duration,
+		}
+		if len(t.Location) > 0 {
+			entry.Location = xspfLocation(t.Location[0])
+		}
+		entries = append(entries, entry)
+	}
+
+	return strings.TrimSpace(doc.Title), entries, nil
+
+}
+
+// xspfLocation reads the location of an xspf track, which is a uri,
+// so relative locations are percent-encoded just like file: urls
+func xspfLocation(location string) string {
+
+	location = strings.TrimSpace(location)
+	if strings.Contains(location, "://") || strings.HasPrefix(strings.ToLower(location), "file:") {
+		return location
+	}
+	if unescaped, err := url.PathUnescape(location); nil == err {
+		return unescaped
+	}
+	return location
+
+}
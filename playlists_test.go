@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseM3U(t *testing.T) {
+
+	entries, err := parseM3U(strings.NewReader(
+		"#EXTM3U\n#EXTINF:,Some Band - First Song\nfirst.mp3\n#EXTINF:-1,Untimed\nsecond.mp3\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Title != "First Song" || entries[0].Artist != "Some Band" || entries[0].Duration != 0 {
+		t.Errorf("an empty duration should still read the title, got %+v", entries[0])
+	}
+	if entries[1].Title != "Untimed" || entries[1].Duration != 0 {
+		t.Errorf("a negative duration should be ignored, got %+v", entries[1])
+	}
+
+}
+
+func TestParseM3UExtensions(t *testing.T) {
+
+	entries, err := parseM3U(strings.NewReader(
+		"\ufeff#EXTM3U\n#EXTINF:215 tvg-id=\"x\",Caf\xe9 - Song\n#EXTALB:An Album\nmusic/song.mp3\n" +
+			"#EXTART:The Artist\n#EXTINF:90,Title - With Dash\n\n# a comment\nother.flac\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := []playlistEntry{
+		{Location: "music/song.mp3", Title: "Song", Artist: "Café", Album: "An Album", Duration: 215000},
+		{Location: "other.flac", Title: "Title - With Dash", Artist: "The Artist", Duration: 90000},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+	}
+	for i, e := range expected {
+		if *entries[i] != e {
+			t.Errorf("expected entry %d to be %+v, got %+v", i, e, *entries[i])
+		}
+	}
+
+}
+
+func TestParsePLS(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected []playlistEntry
+	}{
+		{
+			"[playlist]\nFile2=b.mp3\nTitle2=Second\nLength2=-1\nFile1=a.mp3\nTitle1=Band - First\nLength1=61\nNumberOfEntries=2\nVersion=2\n",
+			[]playlistEntry{
+				{Location: "a.mp3", Title: "First", Artist: "Band", Duration: 61000},
+				{Location: "b.mp3", Title: "Second"},
+			},
+		},
+		{
+			// keys are case insensitive and unknown or unnumbered keys are skipped
+			"[playlist]\nFILE1 = c.ogg\nfilex=ignored\ntitle=ignored\nLength1=abc\n",
+			[]playlistEntry{
+				{Location: "c.ogg"},
+			},
+		},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		entries, err := parsePLS(strings.NewReader(test.input))
+		if nil != err {
+			t.Fatal(err)
+		}
+		if len(entries) != len(test.expected) {
+			t.Errorf("expected %d entries for %q, got %d", len(test.expected), test.input, len(entries))
+			continue
+		}
+		for i, e := range test.expected {
+			if *entries[i] != e {
+				t.Errorf("expected entry %d of %q to be %+v, got %+v", i, test.input, e, *entries[i])
+			}
+		}
+	}
+
+}
+
+func TestParseXSPF(t *testing.T) {
+
+	title, entries, err := parseXSPF(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title> Mix </title>
+  <trackList>
+    <track>
+      <location>file:///music/a.flac</location>
+      <location>http://example.com/a.flac</location>
+      <title>First</title>
+      <creator> Band </creator>
+      <album>Album</album>
+      <duration>123456</duration>
+    </track>
+    <track>
+      <title>No Location</title>
+    </track>
+    <track>
+      <location>music/Some%20Band%20-%20Caf%C3%A9.mp3</location>
+    </track>
+  </trackList>
+</playlist>`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if title != "Mix" {
+		t.Errorf("expected title Mix, got %q", title)
+	}
+
+	expected := []playlistEntry{
+		{Location: "file:///music/a.flac", Title: "First", Artist: "Band", Album: "Album", Duration: 123456},
+		{Title: "No Location"},
+		{Location: "music/Some Band - Café.mp3"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d", len(expected), len(entries))
+	}
+	for i, e := range expected {
+		if *entries[i] != e {
+			t.Errorf("expected entry %d to be %+v, got %+v", i, e, *entries[i])
+		}
+	}
+
+	if _, _, err := parseXSPF(strings.NewReader(`<playlist><trackList><track>`)); nil == err {
+		t.Errorf("expected an error for truncated xml")
+	}
+
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// plistDict is a decoded plist <dict> element
+type plistDict map[string]interface{}
+
+// readPlistFile decodes the property list xml file at the given
+// path into generic go values (dicts, arrays, strings, ints, etc)
+func readPlistFile(path string) (plistDict, error) {
+
+	f, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoder := xml.NewDecoder(f)
+	for {
+		tok, err := decoder.Token()
+		if nil != err {
+			return nil, fmt.Errorf("invalid plist %s: %s", path, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local == "plist" {
+			continue
+		}
+		value, err := decodePlistValue(decoder, start)
+		if nil != err {
+			return nil, fmt.Errorf("invalid plist %s: %s", path, err)
+		}
+		dict, ok := value.(plistDict)
+		if !ok {
+			return nil, fmt.Errorf("invalid plist %s: root element is not a dict", path)
+		}
+		return dict, nil
+	}
+
+}
+
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+
+	switch start.Name.Local {
+
+	case "dict":
+		dict := make(plistDict)
+		key := ""
+		for {
+			tok, err := decoder.Token()
+			if nil != err {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err = decoder.DecodeElement(&key, &t); nil != err {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(decoder, t)
+				if nil != err {
+					return nil, err
+				}
+				dict[key] = value
+			}
+		}
+
+	case "array":
+		var array []interface{}
+		for {
+			tok, err := decoder.Token()
+			if nil != err {
+				return nil, err
+			}
+			switch t := tok.(type) {
+			case xml.EndElement:
+				return array, nil
+			case xml.StartElement:
+				value, err := decodePlistValue(decoder, t)
+				if nil != err {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+		}
+
+	case "true":
+		return true, decoder.Skip()
+
+	case "false":
+		return false, decoder.Skip()
+
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &start); nil != err {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+
+	switch start.Name.Local {
+	case "integer":
+		return strconv.ParseInt(text, 10, 64)
+	case "real":
+		return strconv.ParseFloat(text, 64)
+	case "date":
+		return time.Parse(time.RFC3339, text)
+	}
+
+	// strings and base64 data are both left as text
+	return text, nil
+
+}
+
+// String returns the string value at key, or "" if not present
+func (d plistDict) String(key string) string {
+	s, _ := d[key].(string)
+	return s
+}
+
+// Int returns the integer value at key, or 0 if not present
+func (d plistDict) Int(key string) int {
+	i, _ := d[key].(int64)
+	return int(i)
+}
+
+// Bool returns the boolean value at key, or false if not present
+func (d plistDict) Bool(key string) bool {
+	b, _ := d[key].(bool)
+	return b
+}
+
+// Dict returns the dict value at key, or nil if not present
+func (d plistDict) Dict(key string) plistDict {
+	dict, _ := d[key].(plistDict)
+	return dict
+}
+
+// Array returns the array value at key, or nil if not present
+func (d plistDict) Array(key string) []interface{} {
+	array, _ := d[key].([]interface{})
+	return array
+}
+
+// keys returns the keys of this dict in sorted order
+func (d plistDict) keys() []string {
+	var keys []string
+	for key := range d {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writePlist encodes the given value as a property list xml document,
+// supporting the same types produced by readPlistFile
+func writePlist(w io.Writer, value interface{}) error {
+
+	_, err := io.WriteString(w, xml.Header+
+		`<!DOCTYPE plist PUBLIC "-//Apple Computer//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">`+"\n"+
+		`<plist version="1.0">`+"\n")
+	if nil != err {
+		return err
+	}
+	if err = writePlistValue(w, value, 0); nil != err {
+		return err
+	}
+	_, err = io.WriteString(w, "</plist>\n")
+	return err
+
+}
+
+func writePlistValue(w io.Writer, value interface{}, depth int) error {
+
+	indent := strings.Repeat("\t", depth)
+	escape := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+
+	var err error
+	switch v := value.(type) {
+
+	case plistDict:
+		if _, err = fmt.Fprintf(w, "%s<dict>\n", indent); nil != err {
+			return err
+		}
+		for _, key := range v.keys() {
+			if _, err = fmt.Fprintf(w, "%s\t<key>%s</key>\n", indent, escape(key)); nil != err {
+				return err
+			}
+			if err = writePlistValue(w, v[key], depth+1); nil != err {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(w, "%s</dict>\n", indent)
+
+	case []interface{}:
+		if _, err = fmt.Fprintf(w, "%s<array>\n", indent); nil != err {
+			return err
+		}
+		for _, item := range v {
+			if err = writePlistValue(w, item, depth+1); nil != err {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(w, "%s</array>\n", indent)
+
+	case bool:
+		if v {
+			_, err = fmt.Fprintf(w, "%s<true/>\n", indent)
+		} else {
+			_, err = fmt.Fprintf(w, "%s<false/>\n", indent)
+		}
+
+	case int:
+		_, err = fmt.Fprintf(w, "%s<integer>%d</integer>\n", indent, v)
+
+	case int64:
+		_, err = fmt.Fprintf(w, "%s<integer>%d</integer>\n", indent, v)
+
+	case float64:
+		_, err = fmt.Fprintf(w, "%s<real>%f</real>\n", indent, v)
+
+	case time.Time:
+		_, err = fmt.Fprintf(w, "%s<date>%s</date>\n", indent, v.UTC().Format(time.RFC3339))
+
+	case string:
+		_, err = fmt.Fprintf(w, "%s<string>%s</string>\n", indent, escape(v))
+
+	default:
+		return fmt.Errorf("unsupported plist value: %T", value)
+
+	}
+	return err
+
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// searchBudget is the most track search requests
+	// that are made while matching a single track
+	searchBudget = 12
+
+	// minStrategyTries is how often a strategy is tried before its
+	// success moves it from its place in the static query order
+	minStrategyTries = 3
+
+	// thresholdConfident is the score that a match must be within, with no
+	// other candidate as close, for the search to stop without asking
+	thresholdConfident = 0.05
+)
+
+// SearchQuery is a spotify query to try for a track, named by the
+// strategy that built it so that its success can be counted
+type SearchQuery struct {
+	Strategy string
+	Query    string
+	Pages    int
+}
+
+// strategyStats counts how often a strategy found a match
+type strategyStats struct {
+	Tried   int
+	Matched int
+}
+
+// QueryStats counts the success of each search strategy over
+// a run, so that the most successful ones can be tried first
+type QueryStats map[string]*strategyStats
+
+// Record counts a query of the given strategy, and whether it
+// found a track within the match threshold
+func (qs QueryStats) Record(strategy string, matched bool) {
+
+	stats, ok := qs[strategy]
+	if !ok {
+		stats = &strategyStats{}
+		qs[strategy] = stats
+	}
+	stats.Tried++
+	if matched {
+		stats.Matched++
+	}
+
+}
+
+// rate estimates how likely a strategy is to find a match, where a
+// strategy that has not been tried often enough is given an even chance
+// so that a single miss does not move it behind the broader strategies
+func (qs QueryStats) rate(strategy string) float64 {
+
+	stats, ok := qs[strategy]
+	if !ok || stats.Tried < minStrategyTries {
+		return 0.5
+	}
+	return float64(stats.Matched+1) / float64(stats.Tried+2)
+
+}
+
+// Plan orders the given queries by the success of their strategies,
+// keeping the given order (most selective first) between equals
+func (qs QueryStats) Plan(queries []SearchQuery) []SearchQuery {
+
+	planned := append([]SearchQuery(nil), queries...)
+	sort.SliceStable(planned, func(a, b int) bool {
+		return qs.rate(planned[a].Strategy) > qs.rate(planned[b].Strategy)
+	})
+	return planned
+
+}
+
+func (qs QueryStats) String() string {
+
+	var names []string
+	for name := range qs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		return qs.rate(names[a]) > qs.rate(names[b])
+	})
+
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d/%d", name, qs[name].Matched, qs[name].Tried))
+	}
+	return strings.Join(parts, ", ")
+
+}
+
+// isConfident checks if the best of the sorted matches is
+// close enough, and far enough ahead, to stop searching
+func isConfident(matched []*MatchedTrack) bool {
+	return len(matched) > 0 &&
+		matched[0].score <= thresholdConfident &&
+		(len(matched) == 1 || matched[1].score > thresholdConfident)
+}
+
+// uniqueQueries drops the queries that repeat an earlier one
+func uniqueQueries(queries []SearchQuery) []SearchQuery {
+
+	var ret []SearchQuery
+	seen := make(map[string]bool)
+	for _, q := range queries {
+		if !seen[q.Query] {
+			ret = append(ret, q)
+			seen[q.Query] = true
+		}
+	}
+	return ret
+
+}
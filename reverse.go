@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// ReverseExporter exports spotify playlists and saved tracks into an
+// itunes importable library xml and m3u8 playlists, by matching each
+// spotify track back against the tracks of an existing library
+type ReverseExporter struct {
+
+	// export settings
+	IncludeSaved bool
+	OutputDir    string
+
+	// cache
+	missingLog *MissingLog
+	matcher    *reverseMatcher
+
+	// runtime
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// NewReverseExporter creates a new reverse exporter for the command
+// program and the library that spotify tracks should be matched to
+func NewReverseExporter(program *SimpleCommandProgram, lib *Library) *ReverseExporter {
+
+	re := &ReverseExporter{
+		IncludeSaved: program.AskYesNo("Include your saved tracks?", true),
+		OutputDir: program.AskStringDefault(
+			"export to folder", itspFile(lib.LibraryFile, "spotify")),
+
+		missingLog: InitMissingLog(lib.LibraryFile),
+		matcher:    newReverseMatcher(lib, InitMatchCache(lib.LibraryFile)),
+		lib:        lib,
+		program:    program,
+	}
+	re.missingLog.LogFile = itspFile(lib.LibraryFile, "reverse.missing")
+
+	return re
+
+}
+
+// Run this exporter with the current configuration
+func (re *ReverseExporter) Run() {
+
+	defer re.missingLog.SaveLog()
+
+	re.program.Log("gathering spotify playlists...")
+
+	type exportList struct {
+		name   string
+		tracks []*itunes.Track
+	}
+	var lists []exportList
+
+	if re.IncludeSaved {
+		re.program.Log("matching saved tracks...")
+		lists = append(lists, exportList{
+			name:   "Spotify Saved Tracks",
+			tracks: re.matchTracks("Spotify Saved Tracks", Session.SavedTracks()),
+		})
+	}
+
+	for _, sList := range Session.UserPlaylists() {
+		re.program.Logf("matching %s...", sList.Name)
+		lists = append(lists, exportList{
+			name:   sList.Name,
+			tracks: re.matchTracks(sList.Name, Session.PlaylistTracks(sList.Owner.ID, sList.ID)),
+		})
+	}
+
+	if err := os.MkdirAll(re.OutputDir, 0755); nil != err {
+		re.program.Errorf("Error creating export folder: %s", err)
+		return
+	}
+
+	var playlists []*itunes.Playlist
+	for _, list := range lists {
+
+		playlists = append(playlists, &itunes.Playlist{
+			Name:          list.name,
+			PlaylistItems: list.tracks,
+		})
+
+		m3uFile := filepath.Join(re.OutputDir, safeFileName(list.name)+".m3u8")
+		if err := WriteM3U(m3uFile, list.tracks); nil != err {
+			re.program.Errorf("Error writing %s: %s", m3uFile, err)
+		}
+
+	}
+
+	xmlFile := filepath.Join(re.OutputDir, "Spotify Library.xml")
+	if err := WriteLibraryXML(xmlFile, playlists); nil != err {
+		re.program.Errorf("Error writing library xml: %s", err)
+		return
+	}
+
+	re.program.Logf("exported %d playlists to %s", len(lists), re.OutputDir)
+
+}
+
+// matchTracks finds the library track for each of the given spotify
+// tracks, logging any that cannot be found as missing from destination
+func (re *ReverseExporter) matchTracks(destination string, tracks []spotify.FullTrack) []*itunes.Track {
+
+	var matched []*itunes.Track
+	for j := range tracks {
+		track := &tracks[j]
+		if track.ID == "" {
+			// local files added to spotify playlists have no id
+			continue
+		}
+		if found := re.matcher.Match(track); nil != found {
+			matched = append(matched, found)
+		} else {
+			re.missingLog.LogSpotify(destination, track)
+		}
+	}
+	return matched
+
+}
+
+// reverseMatcher finds the library tracks that match spotify tracks
+type reverseMatcher struct {
+	bySpotify map[string]*itunes.Track
+	byInitial map[rune][]*itunes.Track
+	matched   map[string]*itunes.Track
+}
+
+func newReverseMatcher(lib *Library, cache *MatchCache) *reverseMatcher {
+
+	rm := &reverseMatcher{
+		bySpotify: make(map[string]*itunes.Track),
+		byInitial: make(map[rune][]*itunes.Track),
+		matched:   make(map[string]*itunes.Track),
+	}
+
+	byPersistentID := make(map[string]*itunes.Track)
+	for _, track := range lib.Tracks {
+		byPersistentID[track.PersistentID] = track
+		initial := titleInitial(track.Name)
+		rm.byInitial[initial] = append(rm.byInitial[initial], track)
+	}
+
+	// previous imports already know which track maps to which
+	for _, cached := range cache.TrackMap {
+		if track, ok := byPersistentID[cached.ItunesPersistentID]; ok && cached.SpotifyID != "" {
+			rm.bySpotify[cached.SpotifyID] = track
+		}
+	}
+
+	return rm
+
+}
+
+// Match finds the library track that best matches the given
+// spotify track, or nil if there is no good enough match
+func (rm *reverseMatcher) Match(test *spotify.FullTrack) *itunes.Track {
+
+	id := test.ID.String()
+	if track, ok := rm.bySpotify[id]; ok {
+		return track
+	}
+	if track, ok := rm.matched[id]; ok {
+		return track
+	}
+
+	var best *itunes.Track
+	bestScore := math.Inf(1)
+	for _, candidate := range rm.byInitial[titleInitial(test.Name)] {
+
+		goal := PreprocessTrackArtists(candidate)
+		score := math.Min(
+			TrackCompare(goal, test, false, false).Total(),
+			TrackCompare(goal, test, false, true).Total(),
+		)
+		if score < bestScore {
+			best, bestScore = candidate, score
+		}
+
+	}
+
+	if bestScore > thresholdMatched {
+		best = nil
+	}
+	rm.matched[id] = best
+	return best
+
+}
+
+// titleInitial returns the first significant letter of a track title,
+// used to narrow down the tracks that need to be compared in detail
+func titleInitial(title string) rune {
+
+	title = strings.ToLower(strings.TrimSpace(title))
+	title = strings.TrimPrefix(title, "the ")
+	title = strings.TrimLeftFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	r, _ := utf8.DecodeRuneInString(title)
+	return r
+
+}
+
+// safeFileName replaces characters that are not allowed in file names
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// WriteM3U writes the given tracks as an extended m3u8 playlist,
+// skipping any tracks that do not have a local file
+func WriteM3U(fileName string, tracks []*itunes.Track) error {
+
+	f, err := os.Create(fileName)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = fmt.Fprintln(f, "#EXTM3U"); nil != err {
+		return err
+	}
+	for _, track := range tracks {
+		if track.Location == "" {
+			continue
+		}
+		_, err = fmt.Fprintf(f, "#EXTINF:%d,%s - %s\n%s\n",
+			track.TotalTime/1000, track.Artist, track.Name,
+			fileURLToPath(track.Location))
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// WriteLibraryXML writes the given playlists and all of their tracks
+// as an itunes library xml file, which can be imported into itunes
+// or Music.app using File > Library > Import Playlist
+func WriteLibraryXML(fileName string, playlists []*itunes.Playlist) error {
+
+	tracks := make(plistDict)
+	var lists []interface{}
+
+	for j, playlist := range playlists {
+
+		var items []interface{}
+		for _, track := range playlist.PlaylistItems {
+			key := fmt.Sprintf("%d", track.TrackID)
+			if _, ok := tracks[key]; !ok {
+				tracks[key] = trackPlist(track)
+			}
+			items = append(items, plistDict{"Track ID": track.TrackID})
+		}
+
+		lists = append(lists, plistDict{
+			"Name":                   playlist.Name,
+			"Playlist ID":            j + 1,
+			"Playlist Persistent ID": fmt.Sprintf("%016X", j+1),
+			"All Items":              true,
+			"Playlist Items":         items,
+		})
+
+	}
+
+	f, err := os.Create(fileName)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+
+	return writePlist(f, plistDict{
+		"Major Version":       1,
+		"Minor Version":       1,
+		"Application Version": "12.0",
+		"Tracks":              tracks,
+		"Playlists":           lists,
+	})
+
+}
+
+// trackPlist builds the itunes xml representation of a track
+func trackPlist(track *itunes.Track) plistDict {
+
+	dict := plistDict{
+		"Track ID":      track.TrackID,
+		"Persistent ID": track.PersistentID,
+		"Name":          track.Name,
+		"Artist":        track.Artist,
+		"Album":         track.Album,
+		"Track Type":    "File",
+	}
+
+	strs := map[string]string{
+		"Album Artist": track.AlbumArtist,
+		"Composer":     track.Composer,
+		"Genre":        track.Genre,
+		"Kind":         track.Kind,
+	}
+	for key, value := range strs {
+		if value != "" {
+			dict[key] = value
+		}
+	}
+
+	ints := map[string]int{
+		"Total Time":   track.TotalTime,
+		"Track Number": track.TrackNumber,
+		"Disc Number":  track.DiscNumber,
+		"Year":         track.Year,
+		"Play Count":   track.PlayCount,
+		"Rating":       track.Rating,
+	}
+	for key, value := range ints {
+		if value != 0 {
+			dict[key] = value
+		}
+	}
+
+	if track.Location != "" {
+		location := track.Location
+		if !strings.HasPrefix(location, "file:") {
+			location = pathToFileURL(location)
+		}
+		dict["Location"] = location
+	}
+
+	return dict
+
+}
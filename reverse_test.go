@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+func TestTitleInitial(t *testing.T) {
+
+	tests := map[string]rune{
+		"First Song":     'f',
+		"  The Song":     's',
+		"Theme":          't',
+		"(Intro) Song":   'i',
+		"...And Then":    'a',
+		"99 Luftballons": '9',
+		"Éclair":         'é',
+	}
+
+	for title, expected := range tests {
+		if actual := titleInitial(title); actual != expected {
+			t.Errorf("expected the initial of %q to be %q, got %q", title, expected, actual)
+		}
+	}
+
+}
+
+func TestReverseMatcher(t *testing.T) {
+
+	tracks := []*itunes.Track{
+		{PersistentID: "first", Name: "First Song", Artist: "The Band", Album: "First Album", TotalTime: 200000},
+		{PersistentID: "second", Name: "Second Song", Artist: "The Band", Album: "First Album", TotalTime: 180000},
+		{PersistentID: "cached", Name: "Renamed Locally", Artist: "Somebody", Album: "Other", TotalTime: 100000},
+	}
+	lib := &Library{Library: &itunes.Library{Tracks: tracks}}
+	cache := &MatchCache{TrackMap: TrackMap{
+		"cached": {ItunesPersistentID: "cached", SpotifyID: "remote"},
+		"second": {ItunesPersistentID: "second"},
+	}}
+	rm := newReverseMatcher(lib, cache)
+
+	spotifyTrack := func(id, name string) *spotify.FullTrack {
+		test := &spotify.FullTrack{}
+		test.ID = spotify.ID(id)
+		test.Name = name
+		test.Artists = []spotify.SimpleArtist{{Name: "The Band"}}
+		test.Album.Name = "First Album"
+		test.Duration = 200000
+		return test
+	}
+
+	if found := rm.Match(spotifyTrack("remote", "Whatever It Is Called")); found != tracks[2] {
+		t.Errorf("expected a cached spotify id to map to its library track, got %+v", found)
+	}
+	if found := rm.Match(spotifyTrack("one", "First Song")); found != tracks[0] {
+		t.Errorf("expected the library track with the same details to match, got %+v", found)
+	}
+	if found := rm.Match(spotifyTrack("missing", "Forgotten Song")); nil != found {
+		t.Errorf("expected a track that is not in the library not to match, got %+v", found)
+	}
+
+	// results are remembered by spotify id
+	rm.byInitial = nil
+	if found := rm.Match(spotifyTrack("one", "First Song")); found != tracks[0] {
+		t.Errorf("expected the previous match to be remembered, got %+v", found)
+	}
+
+}
+
+func TestWriteLibraryXML(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "Spotify Library.xml")
+
+	first := &itunes.Track{
+		TrackID: 7, PersistentID: "AAAA", Name: "First & Song", Artist: "The Band",
+		Album: "First Album", TotalTime: 200000, TrackNumber: 1,
+		Location: filepath.Join(dir, "First Song.mp3"),
+	}
+	second := &itunes.Track{TrackID: 9, PersistentID: "BBBB", Name: "Second Song", Artist: "The Band"}
+	playlists := []*itunes.Playlist{
+		{Name: "Both", PlaylistItems: []*itunes.Track{first, second}},
+		{Name: "Only First", PlaylistItems: []*itunes.Track{first}},
+	}
+	if err = WriteLibraryXML(path, playlists); nil != err {
+		t.Fatal(err)
+	}
+
+	raw, err := readPlistFile(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	tracks := raw.Dict("Tracks")
+	if len(tracks) != 2 {
+		t.Fatalf("expected each track to be written once, got %d", len(tracks))
+	}
+	track := tracks.Dict("7")
+	if track.String("Name") != "First & Song" || track.Int("Total Time") != 200000 || track.Int("Track Number") != 1 {
+		t.Errorf("expected the track details to be written, got %v", track)
+	}
+	if fileURLToPath(track.String("Location")) != first.Location {
+		t.Errorf("expected the location to be written as a file url, got %s", track.String("Location"))
+	}
+	if _, ok := tracks.Dict("9")["Location"]; ok {
+		t.Errorf("expected no location for a track without a file")
+	}
+
+	lists := raw.Array("Playlists")
+	if len(lists) != 2 {
+		t.Fatalf("expected 2 playlists, got %d", len(lists))
+	}
+	both, _ := lists[0].(plistDict)
+	items := both.Array("Playlist Items")
+	if both.String("Name") != "Both" || len(items) != 2 {
+		t.Fatalf("expected the first playlist with both tracks, got %v", both)
+	}
+	if item, _ := items[1].(plistDict); item.Int("Track ID") != 9 {
+		t.Errorf("expected the playlist items to refer to the track ids, got %v", items)
+	}
+
+}
+
+func TestWriteM3U(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "playlist.m3u8")
+
+	location := filepath.Join(dir, "First Song.mp3")
+	tracks := []*itunes.Track{
+		{Name: "First Song", Artist: "The Band", TotalTime: 200500, Location: pathToFileURL(location)},
+		{Name: "Streamed", Artist: "The Band"},
+	}
+	if err = WriteM3U(path, tracks); nil != err {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := parseM3U(f)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := playlistEntry{Location: location, Title: "First Song", Artist: "The Band", Duration: 200000}
+	if len(entries) != 1 {
+		t.Fatalf("expected tracks without a file to be skipped, got %d entries", len(entries))
+	}
+	if *entries[0] != expected {
+		t.Errorf("expected the entry to be %+v, got %+v", expected, *entries[0])
+	}
+
+}
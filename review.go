@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// ReviewQueue holds the tracks that could not be matched with
+// confidence, along with their candidates and where they belong,
+// so that they can be reviewed after an import has finished
+type ReviewQueue struct {
+	QueueFile string `json:"-"`
+	Entries   map[string]*ReviewEntry
+
+	// Inserted holds the original positions of the tracks that
+	// have been reviewed and inserted into each playlist so far
+	Inserted map[string][]int
+}
+
+// ReviewEntry is a single track waiting for review
+type ReviewEntry struct {
+	ItunesTrack        string
+	ItunesPersistentID string
+	Candidates         []ReviewCandidate
+	Placements         []ReviewPlacement
+}
+
+// ReviewCandidate is a possible match for a queued track
+type ReviewCandidate struct {
+	SpotifyTrack string
+	SpotifyID    string
+	Score        float64
+}
+
+// ReviewPlacement is where a queued track would have been added,
+// where an empty playlist id is the saved tracks library
+type ReviewPlacement struct {
+	PlaylistID string
+	Position   int
+}
+
+// InitReviewQueue attempts to load the review queue for the given
+// library file but will return an empty queue if not found
+func InitReviewQueue(libraryPath string) *ReviewQueue {
+
+	rq := &ReviewQueue{
+		QueueFile: itspFile(libraryPath, "review"),
+	}
+
+	jsonData, err := ioutil.ReadFile(rq.QueueFile)
+	if nil == err {
+		if err = json.Unmarshal(jsonData, rq); nil != err {
+			panic(fmt.Sprintf("error unmarshalling review queue: %s\n", rq.QueueFile))
+		}
+	} else if !os.IsNotExist(err) {
+		panic(fmt.Sprintf("error reading review queue: %s\n", rq.QueueFile))
+	}
+
+	if nil == rq.Entries {
+		rq.Entries = make(map[string]*ReviewEntry)
+	}
+	if nil == rq.Inserted {
+		rq.Inserted = make(map[string][]int)
+	}
+	return rq
+
+}
+
+// SaveQueue saves this queue next to the library it was initialized for
+func (rq *ReviewQueue) SaveQueue() error {
+
+	jsonData, err := json.MarshalIndent(rq, "", "  ")
+	if nil != err {
+		fmt.Printf("error marshalling review queue: %s", err)
+		return err
+	}
+
+	return ioutil.WriteFile(rq.QueueFile, jsonData, 0644)
+
+}
+
+// Enqueue adds the given track and its candidates to the queue,
+// replacing the candidates if it was already queued
+func (rq *ReviewQueue) Enqueue(goal *itunes.Track, candidates []*MatchedTrack) {
+
+	entry, ok := rq.Entries[goal.PersistentID]
+	if !ok {
+		entry = &ReviewEntry{
+			ItunesTrack:        ItunesCacheString(goal),
+			ItunesPersistentID: goal.PersistentID,
+		}
+		rq.Entries[goal.PersistentID] = entry
+	}
+
+	entry.Candidates = nil
+	for _, mt := range candidates {
+		if mt.Valid() {
+			entry.Candidates = append(entry.Candidates, ReviewCandidate{
+				SpotifyTrack: SpotifyCacheString(mt.spotify),
+				SpotifyID:    mt.spotify.ID.String(),
+				Score:        mt.score,
+			})
+		}
+	}
+
+}
+
+// Place records where the queued track with the given persistent id
+// belongs, returning false if the track is not queued
+func (rq *ReviewQueue) Place(persistentID, playlistID string, position int) bool {
+
+	entry, ok := rq.Entries[persistentID]
+	if !ok {
+		return false
+	}
+	for _, p := range entry.Placements {
+		if p.PlaylistID == playlistID && p.Position == position {
+			return true
+		}
+	}
+	entry.Placements = append(entry.Placements, ReviewPlacement{
+		PlaylistID: playlistID,
+		Position:   position,
+	})
+	return true
+
+}
+
+// ClearPlacements forgets where the queued tracks belong, since every
+// import creates new playlists and the earlier ones may be gone
+func (rq *ReviewQueue) ClearPlacements() {
+
+	for _, entry := range rq.Entries {
+		entry.Placements = nil
+	}
+	rq.Inserted = make(map[string][]int)
+
+}
+
+// insertPosition is where a track that was originally at the given
+// position belongs in a playlist now, offset by the reviewed tracks
+// that have already been inserted before it
+func (rq *ReviewQueue) insertPosition(playlistID string, position int) int {
+
+	offset := position
+	for _, earlier := range rq.Inserted[playlistID] {
+		if earlier <= position {
+			offset++
+		}
+	}
+	return offset
+
+}
+
+// Reviewer works through the review queue, asking for a decision
+// on each track and adding the chosen tracks where they belong
+type Reviewer struct {
+	mapper  *Importer
+	user    *spotify.PrivateUser
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// NewReviewer creates a new reviewer for the command program and library
+func NewReviewer(program *SimpleCommandProgram, lib *Library) *Reviewer {
+
+	return &Reviewer{
+		mapper:  newTrackMapper(program, lib),
+		lib:     lib,
+		program: program,
+	}
+
+}
+
+// Run this reviewer until the queue is empty or the user is done
+func (r *Reviewer) Run() {
+
+	queue := r.mapper.review
+	defer queue.SaveQueue()
+
+	if len(queue.Entries) == 0 {
+		r.program.Log("there are no tracks waiting for review")
+		return
+	}
+
+	var err error
+	r.user, err = Session.Client().CurrentUser()
+	if nil != err {
+		r.program.Errorf("Error getting current user: %s", err)
+		return
+	}
+
+	byPersistentID := make(map[string]*itunes.Track)
+	for _, track := range r.lib.Tracks {
+		byPersistentID[track.PersistentID] = track
+	}
+
+	var ids []string
+	for id := range queue.Entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	r.mapper.matchNum = 0
+	r.mapper.matchTotal = len(ids)
+	for _, id := range ids {
+
+		entry := queue.Entries[id]
+		r.mapper.matchNum++
+
+		track, ok := byPersistentID[id]
+		if !ok {
+			r.program.Warningf("%s is no longer in the library", entry.ItunesTrack)
+			delete(queue.Entries, id)
+			continue
+		}
+
+		r.program.Logf("            \n%04d/%04d: %s\n",
+			r.mapper.matchNum, r.mapper.matchTotal, ItunesCacheString(track))
+
+		goal := PreprocessTrackArtists(track)
+		match, cache := r.mapper.chooseMappedTrack(goal, r.candidates(goal, entry))
+		if !cache {
+			continue
+		}
+		if nil == match {
+			match = &MatchedTrack{
+				itunes: goal,
+				score:  -1,
+			}
+		}
+		r.mapper.cacheTrack(match)
+
+		if match.Valid() {
+			r.place(queue, entry, match.spotify.ID)
+		}
+		delete(queue.Entries, id)
+		queue.SaveQueue()
+
+	}
+
+	r.program.Logf("%d tracks left to review", len(queue.Entries))
+
+}
+
+// candidates fetches the queued candidates of an entry, scoring them
+// again since the profile or aliases may have changed since they were queued
+func (r *Reviewer) candidates(goal *itunes.Track, entry *ReviewEntry) []*MatchedTrack {
+
+	var fetched []spotify.FullTrack
+	for start := 0; start < len(entry.Candidates); start += 50 {
+
+		end := start + 50
+		if end > len(entry.Candidates) {
+			end = len(entry.Candidates)
+		}
+		var ids []spotify.ID
+		for _, c := range entry.Candidates[start:end] {
+			ids = append(ids, spotify.ID(c.SpotifyID))
+		}
+
+		var tracks []*spotify.FullTrack
+		var err error
+		for {
+			tracks, err = Session.Client().GetTracks(ids...)
+			if Session.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			r.program.Warningf("error getting candidates: %s", err)
+			continue
+		}
+
+		for _, t := range tracks {
+			if nil != t {
+				fetched = append(fetched, *t)
+			}
+		}
+
+	}
+
+	candidates := r.mapper.scoreTracks(fetched, goal)
+	sort.Sort(byScoreAndDate(candidates))
+	return candidates
+
+}
+
+// place adds the chosen track everywhere the entry belongs, moving it
+// from the end of each playlist to its original position, offset by
+// the tracks that were inserted before it
+func (r *Reviewer) place(queue *ReviewQueue, entry *ReviewEntry, id spotify.ID) {
+
+	for _, p := range entry.Placements {
+
+		var err error
+		if p.PlaylistID == "" {
+			for {
+				err = Session.Client().AddTracksToLibrary(id)
+				if Session.ShouldTryAgain(err) {
+					continue
+				}
+				break
+			}
+			if nil != err {
+				r.program.Errorf("Error adding track to library: %s", err)
+			}
+			continue
+		}
+
+		playlistID := spotify.ID(p.PlaylistID)
+		position := queue.insertPosition(p.PlaylistID, p.Position)
+
+		var snapshotID string
+		for {
+			snapshotID, err = Session.Client().AddTracksToPlaylist(r.user.ID, playlistID, id)
+			if Session.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			r.program.Errorf("Error adding track to playlist: %s", err)
+			continue
+		}
+		queue.Inserted[p.PlaylistID] = append(queue.Inserted[p.PlaylistID], p.Position)
+
+		var playlist *spotify.FullPlaylist
+		for {
+			playlist, err = Session.Client().GetPlaylist(r.user.ID, playlistID)
+			if Session.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			r.program.Errorf("Error getting playlist: %s", err)
+			continue
+		}
+
+		last := int(playlist.Tracks.Total) - 1
+		if position >= last {
+			continue
+		}
+		for {
+			_, err = Session.Client().ReorderPlaylistTracks(r.user.ID, playlistID,
+				spotify.PlaylistReorderOptions{
+					SnapshotID:   snapshotID,
+					RangeStart:   last,
+					RangeLength:  1,
+					InsertBefore: position,
+				})
+			if Session.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			r.program.Errorf("Error moving track in playlist: %s", err)
+		}
+
+	}
+
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+func TestReviewQueuePlace(t *testing.T) {
+
+	rq := &ReviewQueue{
+		Entries:  make(map[string]*ReviewEntry),
+		Inserted: make(map[string][]int),
+	}
+	if rq.Place("missing", "", 0) {
+		t.Error("a track that is not queued should not be placed")
+	}
+
+	rq.Enqueue(&itunes.Track{PersistentID: "A", Name: "first song"}, nil)
+	if !rq.Place("A", "list", 3) || !rq.Place("A", "list", 3) || !rq.Place("A", "", 0) {
+		t.Error("expected a queued track to be placed")
+	}
+	if placements := rq.Entries["A"].Placements; len(placements) != 2 {
+		t.Errorf("expected a repeated placement to be recorded once, got %v", placements)
+	}
+
+}
+
+func TestReviewInsertPosition(t *testing.T) {
+
+	rq := &ReviewQueue{Inserted: map[string][]int{"list": {1, 5}}}
+
+	tests := []struct {
+		playlist string
+		position int
+		expected int
+	}{
+		{"list", 0, 0},
+		{"list", 1, 2},
+		{"list", 3, 4},
+		{"list", 5, 7},
+		{"other", 5, 5},
+	}
+	for _, test := range tests {
+		if actual := rq.insertPosition(test.playlist, test.position); actual != test.expected {
+			t.Errorf("expected %d in %s to be inserted at %d, got %d",
+				test.position, test.playlist, test.expected, actual)
+		}
+	}
+
+}
+
+func TestReviewClearPlacements(t *testing.T) {
+
+	rq := &ReviewQueue{
+		Entries:  make(map[string]*ReviewEntry),
+		Inserted: map[string][]int{"old": {2}},
+	}
+	rq.Enqueue(&itunes.Track{PersistentID: "A", Name: "first song"}, nil)
+	rq.Place("A", "old", 3)
+
+	rq.ClearPlacements()
+	if placements := rq.Entries["A"].Placements; len(placements) != 0 {
+		t.Errorf("expected the placements of earlier playlists to be dropped, got %v", placements)
+	}
+	if len(rq.Inserted) != 0 {
+		t.Errorf("expected the insertions into earlier playlists to be dropped, got %v", rq.Inserted)
+	}
+
+	rq.Place("A", "new", 1)
+	if placements := rq.Entries["A"].Placements; len(placements) != 1 || placements[0].PlaylistID != "new" {
+		t.Errorf("expected only the new placement, got %v", placements)
+	}
+
+}
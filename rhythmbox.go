@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// rhythmboxReader reads a Rhythmbox rhythmdb.xml database
+// along with the static playlists from playlists.xml
+// if it is found in the same folder
+type rhythmboxReader struct{}
+
+type rhythmdbEntry struct {
+	Type        string `xml:"type,attr"`
+	Title       string `xml:"title"`
+	Artist      string `xml:"artist"`
+	AlbumArtist string `xml:"album-artist"`
+	Album       string `xml:"album"`
+	Composer    string `xml:"composer"`
+	Genre       string `xml:"genre"`
+	TrackNumber int    `xml:"track-number"`
+	DiscNumber  int    `xml:"disc-number"`
+	Duration    int    `xml:"duration"` // seconds
+	FileSize    int    `xml:"file-size"`
+	Location    string `xml:"location"`
+	PlayCount   int    `xml:"play-count"`
+	Rating      int    `xml:"rating"` // stars
+	Date        int    `xml:"date"`   // julian day
+	Hidden      bool   `xml:"hidden"`
+	MBTrackID   string `xml:"mb-trackid"`
+	MBAlbumID   string `xml:"mb-albumid"`
+	MBArtistID  string `xml:"mb-artistid"`
+}
+
+type rhythmdbPlaylist struct {
+	Name      string   `xml:"name,attr"`
+	Type      string   `xml:"type,attr"`
+	Locations []string `xml:"location"`
+}
+
+func (r *rhythmboxReader) Detect(path string) bool {
+
+	if strings.ToLower(filepath.Ext(path)) != ".xml" {
+		return false
+	}
+	head, err := readFileHead(path, 512)
+	return nil == err && strings.Contains(head, "<rhythmdb")
+
+}
+
+func (r *rhythmboxReader) Read(path string) (*Library, error) {
+
+	f, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	var db struct {
+		Entries []rhythmdbEntry `xml:"entry"`
+	}
+	if err = xml.NewDecoder(f).Decode(&db); nil != err {
+		return nil, err
+	}
+
+	builder := newLibraryBuilder(path, FormatRhythmbox)
+	byLocation := make(map[string]*itunes.Track)
+
+	for _, entry := range db.Entries {
+
+		if entry.Type != "song" || entry.Hidden {
+			continue
+		}
+
+		track := &itunes.Track{
+			Name:        entry.Title,
+			Artist:      entry.Artist,
+			AlbumArtist: entry.AlbumArtist,
+			Album:       entry.Album,
+			Composer:    entry.Composer,
+			Genre:       entry.Genre,
+			TrackNumber: entry.TrackNumber,
+			DiscNumber:  entry.DiscNumber,
+			TotalTime:   entry.Duration * 1000,
+			Size:        entry.FileSize,
+			Location:    fileURLToPath(entry.Location),
+			PlayCount:   entry.PlayCount,
+			Rating:      entry.Rating * 20,
+		}
+		if entry.Date > 0 {
+			// julian days count from January 1st of year 1
+			track.Year = time.Date(1, 1, entry.Date, 0, 0, 0, 0, time.UTC).Year()
+		}
+
+		byLocation[entry.Location] = builder.AddTrack(track, &TrackExtras{
+			MusicBrainzTrackID:  entry.MBTrackID,
+			MusicBrainzAlbumID:  entry.MBAlbumID,
+			MusicBrainzArtistID: entry.MBArtistID,
+		})
+
+	}
+
+	playlistsFile := filepath.Join(filepath.Dir(path), "playlists.xml")
+	pf, err := os.Open(playlistsFile)
+	if nil != err {
+		// playlists are optional
+		return builder.lib, nil
+	}
+	defer pf.Close()
+
+	var playlists struct {
+		Playlists []rhythmdbPlaylist `xml:"playlist"`
+	}
+	if err = xml.NewDecoder(pf).Decode(&playlists); nil != err {
+		return nil, err
+	}
+
+	for _, playlist := range playlists.Playlists {
+
+		// automatic playlists are queries rather than track lists
+		if playlist.Type != "static" {
+			continue
+		}
+
+		var tracks []*itunes.Track
+		for _, location := range playlist.Locations {
+			if track, ok := byLocation[location]; ok {
+				tracks = append(tracks, track)
+			}
+		}
+		builder.AddPlaylist(playlist.Name, tracks)
+
+	}
+
+	return builder.lib, nil
+
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// MatchRules decide which tracks are skipped or forced to a specific
+// spotify track before any matching happens, eg:
+//
+//	{
+//	  "Skip": [
+//	    {"Genre": "Audiobook"},
+//	    {"FileType": "m4b"},
+//	    {"Artist": "Various Artists", "Year": 1997},
+//	    {"Regex": "(?i)\\(demo\\)"}
+//	  ],
+//	  "Force": [
+//	    {"Artist": "Sigur Rós", "Name": "Hoppípolla", "SpotifyID": "6eTGxxQxiTFE6LfZHC33Wm"}
+//	  ],
+//	  "Absent": ["The Beatles Tribute Band"]
+//	}
+type MatchRules struct {
+
+	// tracks that are not imported at all
+	Skip []TrackFilter
+
+	// tracks that always map to the given spotify track
+	Force []ForceRule
+
+	// artists that are not in the spotify catalogue, whose
+	// tracks go straight to the missing report
+	Absent []string
+}
+
+// TrackFilter matches library tracks, where every field that is set
+// must match and text fields are compared case insensitively. Regex
+// is matched against the track's "name (artist)[album]" string
+type TrackFilter struct {
+	PersistentID string `json:",omitempty"`
+	Name         string `json:",omitempty"`
+	Artist       string `json:",omitempty"`
+	Album        string `json:",omitempty"`
+	Genre        string `json:",omitempty"`
+	Kind         string `json:",omitempty"`
+	FileType     string `json:",omitempty"`
+	Year         int    `json:",omitempty"`
+	Regex        string `json:",omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// ForceRule maps the tracks matched by its filter to a spotify track
+type ForceRule struct {
+	TrackFilter
+	SpotifyID string
+}
+
+// LoadMatchRules loads the rules file kept next to the given library,
+// returning no rules if there is none
+func LoadMatchRules(libraryPath string) (*MatchRules, error) {
+
+	rules := &MatchRules{}
+	jsonData, err := ioutil.ReadFile(itspFile(libraryPath, "rules"))
+	if os.IsNotExist(err) {
+		return rules, nil
+	} else if nil != err {
+		return rules, err
+	}
+	if err = json.Unmarshal(jsonData, rules); nil != err {
+		return &MatchRules{}, fmt.Errorf("error reading rules: %s", err)
+	}
+
+	for j := range rules.Skip {
+		if err = rules.Skip[j].compile(); nil != err {
+			return &MatchRules{}, err
+		}
+	}
+	for j := range rules.Force {
+		if err = rules.Force[j].compile(); nil != err {
+			return &MatchRules{}, err
+		}
+	}
+	return rules, nil
+
+}
+
+func (tf *TrackFilter) compile() error {
+
+	if tf.Regex == "" {
+		return nil
+	}
+	var err error
+	tf.regex, err = regexp.Compile(tf.Regex)
+	if nil != err {
+		return fmt.Errorf("invalid rule regex %q: %s", tf.Regex, err)
+	}
+	return nil
+
+}
+
+// Matches checks the given track against this filter,
+// where a filter with nothing set matches nothing
+func (tf *TrackFilter) Matches(track *itunes.Track) bool {
+
+	matched := false
+	for _, field := range [][2]string{
+		{tf.PersistentID, track.PersistentID},
+		{tf.Name, track.Name},
+		{tf.Artist, track.Artist},
+		{tf.Album, track.Album},
+		{tf.Genre, track.Genre},
+		{tf.Kind, track.Kind},
+		{strings.TrimPrefix(tf.FileType, "."), fileType(track)},
+	} {
+		if field[0] == "" {
+			continue
+		}
+		if !strings.EqualFold(strings.TrimSpace(field[0]), strings.TrimSpace(field[1])) {
+			return false
+		}
+		matched = true
+	}
+
+	if tf.Year != 0 {
+		if tf.Year != track.Year {
+			return false
+		}
+		matched = true
+	}
+
+	if nil != tf.regex {
+		if !tf.regex.MatchString(ItunesCacheString(track)) {
+			return false
+		}
+		matched = true
+	}
+
+	return matched
+
+}
+
+// fileType is the extension of the track's file, without the dot
+func fileType(track *itunes.Track) string {
+	return strings.TrimPrefix(filepath.Ext(track.Location), ".")
+}
+
+// ShouldSkip checks if any skip rule matches the track
+func (mr *MatchRules) ShouldSkip(track *itunes.Track) bool {
+
+	for j := range mr.Skip {
+		if mr.Skip[j].Matches(track) {
+			return true
+		}
+	}
+	return false
+
+}
+
+// ForcedID returns the spotify id that the track is forced to, if any
+func (mr *MatchRules) ForcedID(track *itunes.Track) (spotify.ID, bool) {
+
+	for j := range mr.Force {
+		if mr.Force[j].Matches(track) && mr.Force[j].SpotifyID != "" {
+			return spotify.ID(mr.Force[j].SpotifyID), true
+		}
+	}
+	return "", false
+
+}
+
+// IsAbsent checks if the track's artist is marked as
+// not being in the spotify catalogue
+func (mr *MatchRules) IsAbsent(track *itunes.Track) bool {
+
+	for _, name := range mr.Absent {
+		name = strings.TrimSpace(name)
+		if strings.EqualFold(name, strings.TrimSpace(track.Artist)) ||
+			strings.EqualFold(name, strings.TrimSpace(track.AlbumArtist)) {
+			return true
+		}
+	}
+	return false
+
+}
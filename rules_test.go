@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+func TestTrackFilterMatches(t *testing.T) {
+
+	track := &itunes.Track{
+		PersistentID: "ABC123",
+		Name:         "Hoppípolla",
+		Artist:       "Sigur Rós",
+		Album:        "Takk...",
+		Genre:        "Post-Rock",
+		Kind:         "MPEG audio file",
+		Year:         2005,
+		Location:     "/music/Sigur Rós/Takk/03 Hoppípolla.mp3",
+	}
+
+	tests := []struct {
+		filter   TrackFilter
+		expected bool
+	}{
+		{TrackFilter{}, false},
+		{TrackFilter{Artist: "sigur rós"}, true},
+		{TrackFilter{Artist: " Sigur Rós ", Name: "HOPPÍPOLLA"}, true},
+		{TrackFilter{Artist: "Sigur Rós", Name: "Glósóli"}, false},
+		{TrackFilter{PersistentID: "abc123"}, true},
+		{TrackFilter{Genre: "post-rock", Kind: "mpeg audio file"}, true},
+		{TrackFilter{Album: "Takk"}, false},
+		{TrackFilter{FileType: "mp3"}, true},
+		{TrackFilter{FileType: ".MP3"}, true},
+		{TrackFilter{FileType: "m4a"}, false},
+		{TrackFilter{Year: 2005}, true},
+		{TrackFilter{Year: 2005, Artist: "Someone Else"}, false},
+		{TrackFilter{Year: 1999, Artist: "Sigur Rós"}, false},
+		{TrackFilter{Regex: `^Hopp.* \(Sigur`}, true},
+		{TrackFilter{Regex: `\[Takk\.\.\.\]$`}, true},
+		{TrackFilter{Regex: `(?i)\(demo\)`}, false},
+		{TrackFilter{Regex: `Hopp`, Year: 1999}, false},
+		{TrackFilter{Regex: `Hopp`, Artist: "Someone Else"}, false},
+	}
+
+	for _, test := range tests {
+		filter := test.filter
+		if err := filter.compile(); nil != err {
+			t.Fatal(err)
+		}
+		if filter.Matches(track) != test.expected {
+			t.Errorf("expected %+v to match: %v", test.filter, test.expected)
+		}
+	}
+
+	// the year of a filter cannot match a track without one
+	if (&TrackFilter{Year: 2005}).Matches(&itunes.Track{}) {
+		t.Errorf("expected a year filter not to match a track without a year")
+	}
+
+	filter := TrackFilter{Regex: "("}
+	if err := filter.compile(); nil == err {
+		t.Errorf("expected an error for an invalid regex")
+	}
+
+}
+
+func TestMatchRules(t *testing.T) {
+
+	rules := &MatchRules{
+		Skip: []TrackFilter{
+			{Genre: "Audiobook"},
+			{},
+		},
+		Force: []ForceRule{
+			{TrackFilter{Artist: "Sigur Rós", Name: "Hoppípolla"}, ""},
+			{TrackFilter{Artist: "Sigur Rós", Name: "Hoppípolla"}, "6eTGxxQxiTFE6LfZHC33Wm"},
+			{TrackFilter{Artist: "Sigur Rós"}, "other"},
+		},
+		Absent: []string{" The Tribute Band "},
+	}
+
+	book := &itunes.Track{Name: "Chapter 1", Genre: "audiobook"}
+	song := &itunes.Track{Name: "Hoppípolla", Artist: "Sigur Rós"}
+	other := &itunes.Track{Name: "Glósóli", Artist: "Sigur Rós"}
+	tribute := &itunes.Track{Name: "Song", Artist: "Singer", AlbumArtist: "the tribute band"}
+
+	if !rules.ShouldSkip(book) {
+		t.Errorf("expected the audiobook to be skipped")
+	}
+	if rules.ShouldSkip(song) {
+		t.Errorf("expected an empty skip rule not to skip everything")
+	}
+
+	if id, ok := rules.ForcedID(song); !ok || id != "6eTGxxQxiTFE6LfZHC33Wm" {
+		t.Errorf("expected the first rule with an id to force the track, got %q, %v", id, ok)
+	}
+	if id, ok := rules.ForcedID(other); !ok || id != "other" {
+		t.Errorf("expected the artist rule to force the track, got %q, %v", id, ok)
+	}
+	if _, ok := rules.ForcedID(book); ok {
+		t.Errorf("expected no forced id for the audiobook")
+	}
+
+	if !rules.IsAbsent(tribute) {
+		t.Errorf("expected an absent album artist to be matched")
+	}
+	if rules.IsAbsent(song) || rules.IsAbsent(&itunes.Track{}) {
+		t.Errorf("expected only the listed artists to be absent")
+	}
+
+}
+
+func TestLoadMatchRules(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	libraryPath := filepath.Join(dir, "Library.xml")
+
+	rules, err := LoadMatchRules(libraryPath)
+	if nil != err || len(rules.Skip) != 0 {
+		t.Errorf("expected no rules without a rules file, got %+v, %v", rules, err)
+	}
+
+	write := func(content string) {
+		if err := ioutil.WriteFile(itspFile(libraryPath, "rules"), []byte(content), 0644); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"Skip": [{"Regex": "(?i)\\(demo\\)"}], "Force": [{"Name": "Song", "SpotifyID": "id"}]}`)
+	rules, err = LoadMatchRules(libraryPath)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !rules.ShouldSkip(&itunes.Track{Name: "Song (Demo)"}) {
+		t.Errorf("expected the loaded regex to be compiled")
+	}
+	if id, ok := rules.ForcedID(&itunes.Track{Name: "song"}); !ok || id != "id" {
+		t.Errorf("expected the loaded force rule to apply, got %q, %v", id, ok)
+	}
+
+	write(`{"Force": [{"Regex": "(", "SpotifyID": "id"}]}`)
+	if _, err = LoadMatchRules(libraryPath); nil == err {
+		t.Errorf("expected an error for an invalid regex")
+	}
+
+	write(`{"Skip": `)
+	if _, err = LoadMatchRules(libraryPath); nil == err {
+		t.Errorf("expected an error for invalid json")
+	}
+
+}
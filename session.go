@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zmb3/spotify"
@@ -42,6 +43,12 @@ type session struct {
 
 	port       int
 	cbListener net.Listener
+	mux        *http.ServeMux
+
+	// the country of the current user, found once as
+	// searches are made from concurrent web requests
+	market     string
+	marketOnce sync.Once
 }
 
 // Session is the singleton instance managing the spotify
@@ -67,8 +74,8 @@ func initSession() error {
 // after the ui is initialized
 func (s *session) start() {
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/auth-callback", s.authCallback)
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/auth-callback", s.authCallback)
 
 	var err error
 	for i, port := range ports {
@@ -81,7 +88,7 @@ func (s *session) start() {
 		}
 	}
 
-	go http.Serve(s.cbListener, mux)
+	go http.Serve(s.cbListener, s.mux)
 
 	auth := spotify.NewAuthenticator(s.getRedirectURL(), scopes...)
 	s.auth = &auth
@@ -89,6 +96,17 @@ func (s *session) start() {
 
 }
 
+// Handle adds a handler to the local server that is
+// used for the authentication callback
+func (s *session) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// LocalURL returns the url of the given path on the local server
+func (s *session) LocalURL(path string) string {
+	return fmt.Sprintf("http://localhost:%d%s", s.port, path)
+}
+
 func (s *session) ShouldTryAgain(err error) bool {
 	if nil == err {
 		return false
@@ -110,23 +128,53 @@ func (s *session) Client() *spotify.Client {
 	return s.client
 }
 
+// Market is the country of the current user, which searches are made
+// for so that tracks are relinked to versions playable there, or
+// empty if it could not be found
+func (s *session) Market() string {
+
+	s.marketOnce.Do(func() {
+		var user *spotify.PrivateUser
+		var err error
+		for {
+			user, err = s.Client().CurrentUser()
+			if s.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			fmt.Printf("failed to get the user's country, searching all markets: %s\n", err)
+			return
+		}
+		s.market = user.Country
+	})
+	return s.market
+
+}
+
 // SearchTracks searches for track results based on the given spotify
-// query, and collects up to 'pages' number of result pages (-1 for all)
+// query, and collects up to 'pages' number of result pages (-1 for all),
+// making one request for each page
 func (s *session) SearchTracks(query string, pages int) []spotify.FullTrack {
 
 	var tracks []spotify.FullTrack
 	var results *spotify.SearchResult
 	var err error
 	limit := pages * 20
+	opts := &spotify.Options{
+		Limit: &limit,
+	}
+	if market := s.Market(); market != "" {
+		opts.Country = &market
+	}
 
 	for {
 		fmt.Printf("searching...\r")
 		results, err = s.Client().SearchOpt(
 			query,
 			spotify.SearchTypeTrack,
-			&spotify.Options{
-				Limit: &limit,
-			},
+			opts,
 		)
 		if Session.ShouldTryAgain(err) {
 			continue
@@ -134,15 +182,18 @@ func (s *session) SearchTracks(query string, pages int) []spotify.FullTrack {
 		break
 	}
 	if err != nil {
-		fmt.Println("%s", err)
+		fmt.Printf("failed to search for %s: %s\n", query, err)
 		return tracks
 	}
 
 	//fmt.Printf(" [%04d]\n", results.Tracks.Total)
 
 	for i := 0; i < pages || pages == -1; i++ {
+		tracks = append(tracks, results.Tracks.Tracks...)
+		if i+1 == pages {
+			break
+		}
 		for {
-			tracks = append(tracks, results.Tracks.Tracks...)
 			err = s.Client().NextTrackResults(results)
 			if err == spotify.ErrNoMorePages {
 				return tracks
@@ -162,6 +213,197 @@ func (s *session) SearchTracks(query string, pages int) []spotify.FullTrack {
 
 }
 
+// SearchAlbums searches for album results based on the given
+// spotify query, returning up to the first 20 albums
+func (s *session) SearchAlbums(query string) []spotify.SimpleAlbum {
+
+	var results *spotify.SearchResult
+	var err error
+	limit := 20
+	opts := &spotify.Options{
+		Limit: &limit,
+	}
+	if market := s.Market(); market != "" {
+		opts.Country = &market
+	}
+
+	for {
+		fmt.Printf("searching...\r")
+		results, err = s.Client().SearchOpt(
+			query,
+			spotify.SearchTypeAlbum,
+			opts,
+		)
+		if Session.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err {
+		fmt.Printf("failed to search albums for %s: %s\n", query, err)
+		return nil
+	}
+	if nil == results.Albums {
+		return nil
+	}
+	return results.Albums.Albums
+
+}
+
+// UserPlaylists collects all of the playlists that the current
+// user owns or follows
+func (s *session) UserPlaylists() []spotify.SimplePlaylist {
+
+	var playlists []spotify.SimplePlaylist
+	limit := 50
+	for offset := 0; ; offset += limit {
+		var page *spotify.SimplePlaylistPage
+		var err error
+		for {
+			page, err = s.Client().CurrentUsersPlaylistsOpt(&spotify.Options{
+				Limit:  &limit,
+				Offset: &offset,
+			})
+			if s.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			fmt.Printf("failed to get playlists: %s\n", err)
+			return playlists
+		}
+		playlists = append(playlists, page.Playlists...)
+		if len(page.Playlists) < limit {
+			return playlists
+		}
+	}
+
+}
+
+// PlaylistTracks collects all tracks in the given playlist, where
+// ownerID is the id of the user that owns the playlist
+func (s *session) PlaylistTracks(ownerID string, playlistID spotify.ID) []spotify.FullTrack {
+
+	var tracks []spotify.FullTrack
+	limit := 100
+	for offset := 0; ; offset += limit {
+		var page *spotify.PlaylistTrackPage
+		var err error
+		for {
+			page, err = s.Client().GetPlaylistTracksOpt(ownerID, playlistID, &spotify.Options{
+				Limit:  &limit,
+				Offset: &offset,
+			}, "")
+			if s.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			fmt.Printf("failed to get playlist tracks: %s\n", err)
+			return tracks
+		}
+		for _, t := range page.Tracks {
+			tracks = append(tracks, t.Track)
+		}
+		if len(page.Tracks) < limit {
+			return tracks
+		}
+	}
+
+}
+
+// SetPlaylistTracks replaces all of the tracks in the given playlist
+// with the ones given, in order, and returns the new snapshot id
+func (s *session) SetPlaylistTracks(userID string, playlistID spotify.ID, ids []spotify.ID) (string, error) {
+
+	first := ids
+	if len(first) > 100 {
+		first = first[:100]
+	}
+
+	var err error
+	for {
+		err = s.Client().ReplacePlaylistTracks(userID, playlistID, first...)
+		if s.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err {
+		return "", err
+	}
+
+	var snapshotID string
+	for start := 100; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		for {
+			snapshotID, err = s.Client().AddTracksToPlaylist(userID, playlistID, ids[start:end]...)
+			if s.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			return "", err
+		}
+	}
+	if snapshotID != "" {
+		return snapshotID, nil
+	}
+
+	// replacing tracks does not report the new snapshot
+	var playlist *spotify.FullPlaylist
+	for {
+		playlist, err = s.Client().GetPlaylist(userID, playlistID)
+		if s.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err {
+		return "", err
+	}
+	return playlist.SnapshotID, nil
+
+}
+
+// SavedTracks collects all tracks saved in the current user's library
+func (s *session) SavedTracks() []spotify.FullTrack {
+
+	var tracks []spotify.FullTrack
+	limit := 50
+	for offset := 0; ; offset += limit {
+		var page *spotify.SavedTrackPage
+		var err error
+		for {
+			page, err = s.Client().CurrentUsersTracksOpt(&spotify.Options{
+				Limit:  &limit,
+				Offset: &offset,
+			})
+			if s.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			fmt.Printf("failed to get saved tracks: %s\n", err)
+			return tracks
+		}
+		for _, t := range page.Tracks {
+			tracks = append(tracks, t.FullTrack)
+		}
+		if len(page.Tracks) < limit {
+			return tracks
+		}
+	}
+
+}
+
 // IsAuthenticated returns true if this session is logged in successfully
 func (s *session) IsAuthenticated() bool {
 	return (s.client != nil)
@@ -216,6 +458,15 @@ func (s *session) Authenticate() error {
 	url := s.auth.AuthURL(s.id)
 	//Console.Debug("auth at: " + url)
 
+	openBrowser(url)
+	return nil
+
+}
+
+// openBrowser opens the given url in the default browser, or
+// asks the user to open it if that is not possible
+func openBrowser(url string) {
+
 	switch runtime.GOOS {
 	case "linux":
 		exec.Command("xdg-open", url).Start()
@@ -224,11 +475,9 @@ func (s *session) Authenticate() error {
 	case "windows":
 		exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
 	default:
-		fmt.Printf("please visit this URL to login: %s\n", url)
+		fmt.Printf("please visit this URL: %s\n", url)
 	}
 
-	return nil
-
 }
 
 func (s *session) Logout() error {
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+// strawberryReader reads the sqlite database of Strawberry or
+// Clementine, which share most of their schema but name the
+// location and collection columns differently
+type strawberryReader struct{}
+
+func (r *strawberryReader) Detect(path string) bool {
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+	default:
+		return false
+	}
+	head, err := readFileHead(path, 16)
+	return nil == err && strings.HasPrefix(head, "SQLite format 3")
+
+}
+
+func (r *strawberryReader) Read(path string) (*Library, error) {
+
+	// the path is escaped so that a ? or # in it is not read as options,
+	// and windows paths become file:///C:/... like any other file url
+	uriPath := filepath.ToSlash(path)
+	if !strings.HasPrefix(uriPath, "/") {
+		uriPath = "/" + uriPath
+	}
+	uri := &url.URL{Scheme: "file", Path: uriPath, RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite3", uri.String())
+	if nil != err {
+		return nil, err
+	}
+	defer db.Close()
+
+	songColumns, err := sqliteColumns(db, "songs")
+	if nil != err {
+		return nil, err
+	}
+	itemColumns, err := sqliteColumns(db, "playlist_items")
+	if nil != err {
+		return nil, err
+	}
+
+	format := FormatStrawberry
+	urlColumn := "url"
+	idColumn := "collection_id"
+	if !songColumns["url"] {
+		format = FormatClementine
+		urlColumn = "filename"
+		idColumn = "library_id"
+	}
+
+	builder := newLibraryBuilder(path, format)
+	byID := make(map[int64]*itunes.Track)
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT ROWID,
+		COALESCE(title, ''), COALESCE(artist, ''), COALESCE(albumartist, ''),
+		COALESCE(album, ''), COALESCE(composer, ''), COALESCE(genre, ''),
+		COALESCE(year, 0), COALESCE(track, 0), COALESCE(disc, 0),
+		COALESCE(length, 0), COALESCE(%s, ''), COALESCE(rating, 0),
+		COALESCE(playcount, 0), COALESCE(compilation, 0)
+		FROM songs WHERE unavailable = 0`, urlColumn))
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var id, length int64
+		var location string
+		var rating float64
+		track := &itunes.Track{}
+		err = rows.Scan(&id,
+			&track.Name, &track.Artist, &track.AlbumArtist,
+			&track.Album, &track.Composer, &track.Genre,
+			&track.Year, &track.TrackNumber, &track.DiscNumber,
+			&length, &location, &rating,
+			&track.PlayCount, &track.Compilation)
+		if nil != err {
+			return nil, err
+		}
+
+		// lengths are stored in nanoseconds and ratings from 0 to 1
+		track.TotalTime = int(length / 1000000)
+		track.Location = fileURLToPath(location)
+		if rating > 0 {
+			track.Rating = int(rating * 100)
+		}
+		// years and track numbers use -1 for unknown
+		if track.Year < 0 {
+			track.Year = 0
+		}
+		if track.TrackNumber < 0 {
+			track.TrackNumber = 0
+		}
+		if track.DiscNumber < 0 {
+			track.DiscNumber = 0
+		}
+
+		byID[id] = builder.AddTrack(track, nil)
+
+	}
+	if err = rows.Err(); nil != err {
+		return nil, err
+	}
+
+	if !itemColumns["playlist"] {
+		return builder.lib, nil
+	}
+
+	names := make(map[int64]string)
+	var order []int64
+	playlists, err := db.Query("SELECT ROWID, COALESCE(name, '') FROM playlists ORDER BY ROWID")
+	if nil != err {
+		return nil, err
+	}
+	defer playlists.Close()
+	for playlists.Next() {
+		var id int64
+		var name string
+		if err = playlists.Scan(&id, &name); nil != err {
+			return nil, err
+		}
+		names[id] = name
+		order = append(order, id)
+	}
+
+	items, err := db.Query(fmt.Sprintf(`SELECT playlist, COALESCE(%s, -1),
+		COALESCE(title, ''), COALESCE(artist, ''), COALESCE(album, ''),
+		COALESCE(length, 0), COALESCE(%s, '')
+		FROM playlist_items ORDER BY playlist, ROWID`, idColumn, urlColumn))
+	if nil != err {
+		return nil, err
+	}
+	defer items.Close()
+
+	byPlaylist := make(map[int64][]*itunes.Track)
+	for items.Next() {
+
+		var playlist, id, length int64
+		var location string
+		track := &itunes.Track{}
+		err = items.Scan(&playlist, &id,
+			&track.Name, &track.Artist, &track.Album, &length, &location)
+		if nil != err {
+			return nil, err
+		}
+
+		// items from the collection refer to a song, anything
+		// else (eg: files outside of it) has its own metadata
+		if existing, ok := byID[id]; ok {
+			byPlaylist[playlist] = append(byPlaylist[playlist], existing)
+			continue
+		}
+		if track.Name == "" {
+			continue
+		}
+		track.TotalTime = int(length / 1000000)
+		track.Location = fileURLToPath(location)
+		byPlaylist[playlist] = append(byPlaylist[playlist], builder.AddTrack(track, nil))
+
+	}
+	if err = items.Err(); nil != err {
+		return nil, err
+	}
+
+	for _, id := range order {
+		if tracks := byPlaylist[id]; len(tracks) > 0 {
+			builder.AddPlaylist(names[id], tracks)
+		}
+	}
+
+	return builder.lib, nil
+
+}
+
+// sqliteColumns returns the set of column names in the given table
+func sqliteColumns(db *sql.DB, table string) (map[string]bool, error) {
+
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, kind string
+		var def sql.NullString
+		if err = rows.Scan(&cid, &name, &kind, &notNull, &def, &pk); nil != err {
+			return nil, err
+		}
+		columns[name] = true
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no %s table found", table)
+	}
+	return columns, rows.Err()
+
+}
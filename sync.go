@@ -0,0 +1,612 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// SyncPolicy decides what happens to a playlist that was changed
+// both in the library and on spotify since the last sync
+type SyncPolicy string
+
+// all available sync conflict policies
+const (
+	SyncMerge   SyncPolicy = "merge"
+	SyncItunes  SyncPolicy = "itunes"
+	SyncSpotify SyncPolicy = "spotify"
+)
+
+// apply decides which sides of a playlist are applied to the other
+// given which of them changed, only using the policy on a conflict
+func (p SyncPolicy) apply(libChanged, spChanged bool) (applyLib, applySpotify bool) {
+
+	applyLib, applySpotify = libChanged, spChanged
+	if libChanged && spChanged {
+		switch p {
+		case SyncItunes:
+			applySpotify = false
+		case SyncSpotify:
+			applyLib = false
+		}
+	}
+	return applyLib, applySpotify
+
+}
+
+// lovedRating is the rating (4 stars) at which tracks are
+// saved to the spotify library when syncing ratings
+const lovedRating = 80
+
+// SyncSnapshot is the state of a library and its spotify playlists
+// as of the last sync, which is used to find what changed on each side
+type SyncSnapshot struct {
+	SnapshotFile string `json:"-"`
+	Playlists    map[string]*SyncedPlaylist
+	Ratings      map[string]int
+	Tracks       []string
+}
+
+// SyncedPlaylist is the last synced state of a single playlist,
+// with library tracks by persistent id and spotify tracks by id
+type SyncedPlaylist struct {
+	ItunesTracks      []string
+	SpotifyID         string
+	SpotifySnapshotID string
+	SpotifyTracks     []string
+}
+
+// InitSyncSnapshot attempts to load the sync snapshot for the given
+// library file but will return an empty snapshot if not found
+func InitSyncSnapshot(libraryPath string) *SyncSnapshot {
+
+	ss := &SyncSnapshot{
+		SnapshotFile: itspFile(libraryPath, "snapshot"),
+	}
+
+	jsonData, err := ioutil.ReadFile(ss.SnapshotFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("no sync snapshot found for: %s\n", libraryPath)
+	} else if nil != err {
+		panic(fmt.Sprintf("error reading sync snapshot: %s\n", ss.SnapshotFile))
+	} else if err = json.Unmarshal(jsonData, ss); nil != err {
+		panic(fmt.Sprintf("error unmarshalling sync snapshot: %s\n", ss.SnapshotFile))
+	}
+
+	if nil == ss.Playlists {
+		ss.Playlists = make(map[string]*SyncedPlaylist)
+	}
+	if nil == ss.Ratings {
+		ss.Ratings = make(map[string]int)
+	}
+	return ss
+
+}
+
+// SaveSnapshot saves this snapshot next to the library it was
+// initialized for (overwriting the previous snapshot)
+func (ss *SyncSnapshot) SaveSnapshot() error {
+
+	jsonData, err := json.MarshalIndent(ss, "", "  ")
+	if nil != err {
+		fmt.Printf("error marshalling sync snapshot: %s", err)
+		return err
+	}
+
+	return ioutil.WriteFile(ss.SnapshotFile, jsonData, 0644)
+
+}
+
+// Syncer keeps the library playlists and spotify playlists in step,
+// applying only what changed on each side since the last sync.
+// Changes from spotify cannot be written into itunes directly, so
+// they are written as xml and m3u8 playlists to be imported instead
+type Syncer struct {
+
+	// sync settings
+	Policy        SyncPolicy
+	SyncRatings   bool
+	SaveNewTracks bool
+	OutputDir     string
+
+	// cache
+	snapshot *SyncSnapshot
+	mapper   *Importer
+	matcher  *reverseMatcher
+
+	// runtime
+	user    *spotify.PrivateUser
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// NewSyncer creates a new syncer for the command program and
+// library that are supplied
+func NewSyncer(program *SimpleCommandProgram, lib *Library) *Syncer {
+
+	mapper := newTrackMapper(program, lib)
+	mapper.DeferReview = program.AskYesNo("Queue uncertain matches to review later instead of asking now?", false)
+	return newSyncer(program, lib, mapper)
+
+}
+
+// newSyncer asks for the sync settings of a syncer using the given mapper
+func newSyncer(program *SimpleCommandProgram, lib *Library, mapper *Importer) *Syncer {
+
+	policies := []SyncPolicy{SyncMerge, SyncItunes, SyncSpotify}
+	policy := policies[program.AskOptionDefault(
+		"when a playlist was changed in both places",
+		[]string{
+			"merge the changes from both",
+			"keep the library version",
+			"keep the spotify version",
+		}, 0)]
+
+	return &Syncer{
+		Policy:        policy,
+		SyncRatings:   program.AskYesNo("Save tracks rated 4 stars or more to your Spotify library?", false),
+		SaveNewTracks: program.AskYesNo("Save tracks newly added to the library to your Spotify library?", false),
+		OutputDir: program.AskStringDefault(
+			"write spotify changes to folder", itspFile(lib.LibraryFile, "sync")),
+
+		snapshot: InitSyncSnapshot(lib.LibraryFile),
+		mapper:   mapper,
+		matcher:  newReverseMatcher(lib, mapper.matchCache),
+		lib:      lib,
+		program:  program,
+	}
+
+}
+
+// SetLibrary replaces the library being synced with a newer read
+// of the same library, keeping the settings and match cache
+func (s *Syncer) SetLibrary(lib *Library) {
+
+	s.lib = lib
+	s.mapper.lib = lib
+	s.mapper.matchTotal = len(lib.Tracks)
+	s.mapper.trackCache = make(map[int]*MatchedTrack)
+	s.mapper.missingLog.Entries = make(map[string][]string)
+	s.mapper.missingLog.Flags = make(map[string][]string)
+	s.matcher = newReverseMatcher(lib, s.mapper.matchCache)
+
+}
+
+// Run this syncer with the current configuration
+func (s *Syncer) Run() {
+
+	defer s.mapper.missingLog.SaveLog()
+	defer s.mapper.review.SaveQueue()
+
+	var err error
+	s.user, err = Session.Client().CurrentUser()
+	if nil != err {
+		s.program.Errorf("Error getting current user: %s", err)
+		return
+	}
+
+	s.program.Log("syncing playlists...")
+
+	var changed []*itunes.Playlist
+	seen := make(map[string]bool)
+	for _, iList := range s.lib.Playlists {
+
+		if s.mapper.shouldSkipPlaylist(iList) || seen[iList.Name] {
+			continue
+		}
+		seen[iList.Name] = true
+
+		updated, err := s.syncPlaylist(iList)
+		if nil != err {
+			s.program.Errorf("Error syncing %s: %s", iList.Name, err)
+			continue
+		}
+		if nil != updated {
+			changed = append(changed, updated)
+		}
+		s.snapshot.SaveSnapshot()
+
+	}
+
+	for name := range s.snapshot.Playlists {
+		if !seen[name] {
+			s.program.Warningf("%s is no longer in the library, its spotify playlist was left as is", name)
+			delete(s.snapshot.Playlists, name)
+		}
+	}
+
+	if s.SyncRatings {
+		s.syncRatings()
+	}
+
+	s.syncNewTracks()
+
+	s.snapshot.SaveSnapshot()
+
+	if len(changed) == 0 {
+		s.program.Log("library is up to date with spotify")
+		return
+	}
+
+	if err = os.MkdirAll(s.OutputDir, 0755); nil != err {
+		s.program.Errorf("Error creating sync folder: %s", err)
+		return
+	}
+	for _, playlist := range changed {
+		m3uFile := filepath.Join(s.OutputDir, safeFileName(playlist.Name)+".m3u8")
+		if err = WriteM3U(m3uFile, playlist.PlaylistItems); nil != err {
+			s.program.Errorf("Error writing %s: %s", m3uFile, err)
+		}
+	}
+	xmlFile := filepath.Join(s.OutputDir, "Synced Playlists.xml")
+	if err = WriteLibraryXML(xmlFile, changed); nil != err {
+		s.program.Errorf("Error writing library xml: %s", err)
+		return
+	}
+
+	s.program.Logf("%d playlists changed on spotify, import %s to update your library",
+		len(changed), xmlFile)
+
+}
+
+// syncPlaylist applies the changes made to the given playlist on either
+// side since the last sync, returning the updated library version of the
+// playlist if it needs to be changed to match spotify
+func (s *Syncer) syncPlaylist(iList *itunes.Playlist) (*itunes.Playlist, error) {
+
+	var libTracks []*itunes.Track
+	var libIDs []string
+	for _, track := range iList.PlaylistItems {
+		if !s.mapper.shouldSkipTrack(track) {
+			libTracks = append(libTracks, track)
+			libIDs = append(libIDs, track.PersistentID)
+		}
+	}
+
+	prev, ok := s.snapshot.Playlists[iList.Name]
+	if !ok || prev.SpotifyID == "" {
+		return nil, s.createPlaylist(iList.Name, libTracks)
+	}
+
+	var playlist *spotify.FullPlaylist
+	var err error
+	for {
+		playlist, err = Session.Client().GetPlaylist(s.user.ID, spotify.ID(prev.SpotifyID))
+		if Session.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	// the snapshot id only changes when the playlist does,
+	// so there is no need to fetch the tracks otherwise
+	spotifyIDs := prev.SpotifyTracks
+	byID := make(map[string]*spotify.FullTrack)
+	if playlist.SnapshotID != prev.SpotifySnapshotID {
+		full := Session.PlaylistTracks(s.user.ID, playlist.ID)
+		spotifyIDs = nil
+		for j := range full {
+			if full[j].ID == "" {
+				continue
+			}
+			spotifyIDs = append(spotifyIDs, full[j].ID.String())
+			byID[full[j].ID.String()] = &full[j]
+		}
+	}
+
+	libAdded, libRemoved := diffIDs(prev.ItunesTracks, libIDs)
+	spAdded, spRemoved := diffIDs(prev.SpotifyTracks, spotifyIDs)
+	libChanged := !equalIDs(prev.ItunesTracks, libIDs)
+	spChanged := !equalIDs(prev.SpotifyTracks, spotifyIDs)
+
+	if libChanged && spChanged {
+		s.program.Warningf("%s was changed in both places, using %s policy", iList.Name, s.Policy)
+	}
+	applyLib, applySpotify := s.Policy.apply(libChanged, spChanged)
+
+	if !applyLib && !applySpotify {
+		prev.ItunesTracks = libIDs
+		prev.SpotifyTracks = spotifyIDs
+		prev.SpotifySnapshotID = playlist.SnapshotID
+		return nil, nil
+	}
+
+	s.program.Logf("syncing %s: library +%d -%d, spotify +%d -%d...", iList.Name,
+		len(libAdded), len(libRemoved), len(spAdded), len(spRemoved))
+
+	snapshotID := playlist.SnapshotID
+	if applyLib {
+
+		// library tracks come first in library order, followed by
+		// anything that is only on spotify in its spotify order
+		removed := make(map[string]bool)
+		for pid := range libRemoved {
+			if id := s.cachedID(pid); id != "" {
+				removed[id] = true
+			}
+		}
+
+		var desired []string
+		inDesired := make(map[string]bool)
+		for _, track := range libTracks {
+			id := string(s.mapper.mappedTrackID(track))
+			if id == "" {
+				s.mapper.missingLog.Log(iList.Name, track)
+				continue
+			}
+			if inDesired[id] || (applySpotify && spRemoved[id]) {
+				continue
+			}
+			desired = append(desired, id)
+			inDesired[id] = true
+		}
+		for _, id := range spotifyIDs {
+			if inDesired[id] || removed[id] || (!applySpotify && spAdded[id]) {
+				continue
+			}
+			desired = append(desired, id)
+			inDesired[id] = true
+		}
+
+		if !equalIDs(desired, spotifyIDs) {
+			ids := make([]spotify.ID, len(desired))
+			for j, id := range desired {
+				ids[j] = spotify.ID(id)
+			}
+			snapshotID, err = Session.SetPlaylistTracks(s.user.ID, playlist.ID, ids)
+			if nil != err {
+				return nil, err
+			}
+			spotifyIDs = desired
+		}
+
+	}
+
+	prev.ItunesTracks = libIDs
+	prev.SpotifyTracks = spotifyIDs
+	prev.SpotifySnapshotID = snapshotID
+
+	if !applySpotify {
+		return nil, nil
+	}
+
+	// the library version follows the spotify order, matching whatever
+	// was added on spotify, dropping what was removed there and keeping
+	// the tracks that are not on spotify at all at the end
+	byCachedID := make(map[string]*itunes.Track)
+	for _, track := range libTracks {
+		if id := s.cachedID(track.PersistentID); id != "" && nil == byCachedID[id] {
+			byCachedID[id] = track
+		}
+	}
+	var final []*itunes.Track
+	inFinal := make(map[string]bool)
+	for _, id := range spotifyIDs {
+		track, ok := byCachedID[id]
+		if !ok {
+			test, ok := byID[id]
+			if !ok || !spAdded[id] {
+				continue
+			}
+			if track = s.matcher.Match(test); nil == track {
+				s.mapper.missingLog.LogSpotify(iList.Name, test)
+				continue
+			}
+		}
+		if !inFinal[track.PersistentID] {
+			final = append(final, track)
+			inFinal[track.PersistentID] = true
+		}
+	}
+	for _, track := range libTracks {
+		if !spRemoved[s.cachedID(track.PersistentID)] && !inFinal[track.PersistentID] {
+			final = append(final, track)
+			inFinal[track.PersistentID] = true
+		}
+	}
+
+	var finalIDs []string
+	for _, track := range final {
+		finalIDs = append(finalIDs, track.PersistentID)
+	}
+	if equalIDs(finalIDs, libIDs) {
+		return nil, nil
+	}
+	return &itunes.Playlist{Name: iList.Name, PlaylistItems: final}, nil
+
+}
+
+// createPlaylist creates a spotify playlist for a library playlist
+// that has not been synced before and records it in the snapshot
+func (s *Syncer) createPlaylist(name string, tracks []*itunes.Track) error {
+
+	s.program.Logf("creating %s...", name)
+
+	var ids []spotify.ID
+	var spotifyIDs, libIDs []string
+	s.mapper.matchNum = 0
+	s.mapper.matchTotal = len(tracks)
+	for _, track := range tracks {
+		libIDs = append(libIDs, track.PersistentID)
+		id := s.mapper.mappedTrackID(track)
+		if id == "" {
+			s.mapper.missingLog.Log(name, track)
+			continue
+		}
+		ids = append(ids, id)
+		spotifyIDs = append(spotifyIDs, id.String())
+	}
+
+	var sList *spotify.FullPlaylist
+	var err error
+	for {
+		sList, err = Session.Client().CreatePlaylistForUser(s.user.ID, name, false)
+		if Session.ShouldTryAgain(err) {
+			continue
+		}
+		break
+	}
+	if nil != err {
+		return err
+	}
+
+	snapshotID, err := Session.SetPlaylistTracks(s.user.ID, sList.ID, ids)
+	if nil != err {
+		return err
+	}
+
+	s.snapshot.Playlists[name] = &SyncedPlaylist{
+		ItunesTracks:      libIDs,
+		SpotifyID:         sList.ID.String(),
+		SpotifySnapshotID: snapshotID,
+		SpotifyTracks:     spotifyIDs,
+	}
+	return nil
+
+}
+
+// syncRatings saves the tracks that have been newly rated at
+// or above lovedRating since the last sync to the spotify library
+func (s *Syncer) syncRatings() {
+
+	s.program.Log("syncing ratings...")
+
+	var ids []spotify.ID
+	for _, track := range s.lib.Tracks {
+
+		if s.mapper.shouldSkipTrack(track) {
+			continue
+		}
+
+		prev, ok := s.snapshot.Ratings[track.PersistentID]
+		s.snapshot.Ratings[track.PersistentID] = track.Rating
+		if track.Rating < lovedRating || (ok && prev >= lovedRating) {
+			continue
+		}
+
+		if id := s.mapper.mappedTrackID(track); id != "" {
+			ids = append(ids, id)
+		}
+
+	}
+
+	s.saveToLibrary(ids)
+	s.program.Logf("saved %d newly rated tracks", len(ids))
+
+}
+
+// syncNewTracks records the tracks in the library and saves any that
+// were added since the last sync to the spotify library, if enabled
+func (s *Syncer) syncNewTracks() {
+
+	known := make(map[string]bool)
+	for _, pid := range s.snapshot.Tracks {
+		known[pid] = true
+	}
+	firstSync := len(s.snapshot.Tracks) == 0
+
+	var added []*itunes.Track
+	s.snapshot.Tracks = nil
+	for _, track := range s.lib.Tracks {
+		if s.mapper.shouldSkipTrack(track) {
+			continue
+		}
+		s.snapshot.Tracks = append(s.snapshot.Tracks, track.PersistentID)
+		if !known[track.PersistentID] {
+			added = append(added, track)
+		}
+	}
+
+	// everything is new the first time, which is what import is for
+	if firstSync || !s.SaveNewTracks || len(added) == 0 {
+		return
+	}
+
+	s.program.Logf("saving %d new tracks...", len(added))
+
+	var ids []spotify.ID
+	s.mapper.matchNum = 0
+	s.mapper.matchTotal = len(added)
+	for _, track := range added {
+		if id := s.mapper.mappedTrackID(track); id != "" {
+			ids = append(ids, id)
+		} else {
+			s.mapper.missingLog.Log("Spotify Library", track)
+		}
+	}
+	s.saveToLibrary(ids)
+
+}
+
+// saveToLibrary adds the given tracks to the spotify library
+func (s *Syncer) saveToLibrary(ids []spotify.ID) {
+
+	for start := 0; start < len(ids); start += 50 {
+		end := start + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+		for {
+			err := Session.Client().AddTracksToLibrary(ids[start:end]...)
+			if Session.ShouldTryAgain(err) {
+				continue
+			} else if err != nil {
+				s.program.Errorf("Error adding tracks to library: %s", err)
+				return
+			}
+			break
+		}
+	}
+
+}
+
+// cachedID returns the spotify id that the track with the
+// given persistent id was last mapped to, if any
+func (s *Syncer) cachedID(persistentID string) string {
+	if cached, ok := s.mapper.matchCache.TrackMap[persistentID]; ok {
+		return cached.SpotifyID
+	}
+	return ""
+}
+
+// diffIDs finds the ids that were added and removed between two lists
+func diffIDs(before, after []string) (added, removed map[string]bool) {
+
+	added = make(map[string]bool)
+	removed = make(map[string]bool)
+	for _, id := range after {
+		added[id] = true
+	}
+	for _, id := range before {
+		if added[id] {
+			delete(added, id)
+		} else {
+			removed[id] = true
+		}
+	}
+	return added, removed
+
+}
+
+// equalIDs checks if two lists hold the same ids in the same order
+func equalIDs(a, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+	for j := range a {
+		if a[j] != b[j] {
+			return false
+		}
+	}
+	return true
+
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestDiffIDs(t *testing.T) {
+
+	added, removed := diffIDs([]string{"a", "b", "c"}, []string{"c", "d", "a"})
+	if len(added) != 1 || !added["d"] {
+		t.Errorf("expected only d to be added, got %v", added)
+	}
+	if len(removed) != 1 || !removed["b"] {
+		t.Errorf("expected only b to be removed, got %v", removed)
+	}
+
+	added, removed = diffIDs([]string{"a", "b"}, []string{"b", "a"})
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("a reorder should add and remove nothing, got +%v -%v", added, removed)
+	}
+
+}
+
+func TestEqualIDs(t *testing.T) {
+
+	tests := []struct {
+		a, b     []string
+		expected bool
+	}{
+		{nil, nil, true},
+		{nil, []string{}, true},
+		{[]string{"a", "b"}, []string{"a", "b"}, true},
+		{[]string{"a", "b"}, []string{"b", "a"}, false},
+		{[]string{"a"}, []string{"a", "b"}, false},
+	}
+	for _, test := range tests {
+		if actual := equalIDs(test.a, test.b); actual != test.expected {
+			t.Errorf("expected equalIDs(%v, %v) to be %v", test.a, test.b, test.expected)
+		}
+	}
+
+}
+
+func TestSyncPolicy(t *testing.T) {
+
+	tests := []struct {
+		policy                 SyncPolicy
+		libChanged, spChanged  bool
+		applyLib, applySpotify bool
+	}{
+		{SyncMerge, true, true, true, true},
+		{SyncItunes, true, true, true, false},
+		{SyncSpotify, true, true, false, true},
+		{SyncItunes, false, true, false, true},
+		{SyncSpotify, true, false, true, false},
+		{SyncMerge, false, false, false, false},
+	}
+	for _, test := range tests {
+		applyLib, applySpotify := test.policy.apply(test.libChanged, test.spChanged)
+		if applyLib != test.applyLib || applySpotify != test.applySpotify {
+			t.Errorf("%s with library %v and spotify %v changed: expected %v/%v, got %v/%v",
+				test.policy, test.libChanged, test.spChanged,
+				test.applyLib, test.applySpotify, applyLib, applySpotify)
+		}
+	}
+
+}
@@ -0,0 +1,914 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	itunes "github.com/rydrman/go-itunes-library"
+)
+
+var errNoID3 = errors.New("no ID3v2 tag found")
+
+// audioExtensions are the file types that tags can be read from
+var audioExtensions = []string{
+	".mp3", ".flac", ".ogg", ".oga", ".opus", ".m4a", ".m4b", ".mp4",
+}
+
+// FileTags holds the metadata that could be read
+// from the embedded tags of a local audio file
+type FileTags struct {
+	Title       string
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Composer    string
+	Genre       string
+	Year        int
+	TrackNumber int
+	DiscNumber  int
+	Duration    int // milliseconds
+	Compilation bool
+
+	ISRC                string
+	MusicBrainzTrackID  string
+	MusicBrainzAlbumID  string
+	MusicBrainzArtistID string
+
+	Work           string
+	MovementName   string
+	MovementNumber int
+	Conductor      string
+
+	Explicit bool
+	Clean    bool
+}
+
+// ReadFileTags reads the embedded tags of the audio file at path,
+// returning an error if the format is not supported
+func ReadFileTags(path string) (*FileTags, error) {
+
+	f, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	tags := &FileTags{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		err = readMP3Tags(f, tags)
+	case ".flac":
+		err = readFLACTags(f, tags)
+	case ".ogg", ".oga", ".opus":
+		err = readOggTags(f, tags)
+	case ".m4a", ".m4b", ".mp4":
+		err = readMP4Tags(f, tags)
+	default:
+		err = fmt.Errorf("unsupported audio file: %s", path)
+	}
+
+	if nil != err {
+		return nil, err
+	}
+	return tags, nil
+
+}
+
+// Apply copies all tag values that are set into the given track
+func (ft *FileTags) Apply(track *itunes.Track) {
+
+	if ft.Title != "" {
+		track.Name = ft.Title
+	}
+	if ft.Artist != "" {
+		track.Artist = ft.Artist
+	}
+	if ft.AlbumArtist != "" {
+		track.AlbumArtist = ft.AlbumArtist
+	}
+	if ft.Album != "" {
+		track.Album = ft.Album
+	}
+	if ft.Composer != "" {
+		track.Composer = ft.Composer
+	}
+	if ft.Genre != "" {
+		track.Genre = ft.Genre
+	}
+	if ft.Year != 0 {
+		track.Year = ft.Year
+	}
+	if ft.TrackNumber != 0 {
+		track.TrackNumber = ft.TrackNumber
+	}
+	if ft.DiscNumber != 0 {
+		track.DiscNumber = ft.DiscNumber
+	}
+	if ft.Duration != 0 {
+		track.TotalTime = ft.Duration
+	}
+	track.Compilation = track.Compilation || ft.Compilation
+
+}
+
+// Extras returns the tag values that do not fit in an itunes track
+func (ft *FileTags) Extras() *TrackExtras {
+	return &TrackExtras{
+		ISRC:                ft.ISRC,
+		MusicBrainzTrackID:  ft.MusicBrainzTrackID,
+		MusicBrainzAlbumID:  ft.MusicBrainzAlbumID,
+		MusicBrainzArtistID: ft.MusicBrainzArtistID,
+		Work:                ft.Work,
+		MovementName:        ft.MovementName,
+		MovementNumber:      ft.MovementNumber,
+		Conductor:           ft.Conductor,
+		Explicit:            ft.Explicit,
+		Clean:               ft.Clean,
+	}
+}
+
+// set stores a tag value by name, where name can be any of the
+// naming conventions used between vorbis comments, ID3 TXXX frames
+// and MP4 freeform atoms (eg: ALBUMARTIST, "Album Artist")
+func (ft *FileTags) set(name, value string) {
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	name = strings.ToLower(name)
+	name = strings.Replace(name, " ", "", -1)
+	name = strings.Replace(name, "_", "", -1)
+
+	switch name {
+	case "title":
+		ft.Title = value
+	case "artist":
+		if ft.Artist != "" {
+			ft.Artist += " & " + value
+		} else {
+			ft.Artist = value
+		}
+	case "albumartist":
+		ft.AlbumArtist = value
+	case "album":
+		ft.Album = value
+	case "composer":
+		ft.Composer = value
+	case "genre":
+		ft.Genre = value
+	case "date", "year":
+		ft.Year = leadingInt(value)
+	case "tracknumber":
+		ft.TrackNumber = leadingInt(value)
+	case "discnumber":
+		ft.DiscNumber = leadingInt(value)
+	case "compilation":
+		ft.Compilation = value == "1"
+	case "isrc":
+		ft.ISRC = strings.ToUpper(value)
+	case "musicbrainztrackid", "musicbrainzrecordingid":
+		ft.MusicBrainzTrackID = value
+	case "musicbrainzalbumid":
+		ft.MusicBrainzAlbumID = value
+	case "musicbrainzartistid":
+		ft.MusicBrainzArtistID = value
+	case "work":
+		ft.Work = value
+	case "movementname":
+		ft.MovementName = value
+	case "movement", "movementnumber":
+		ft.MovementNumber = leadingInt(value)
+	case "conductor":
+		ft.Conductor = value
+	case "itunesadvisory", "contentrating":
+		ft.setAdvisory(leadingInt(value))
+	}
+
+}
+
+// setAdvisory sets the content rating from an itunes advisory
+// value, which is 1 or 4 for explicit and 2 for clean
+func (ft *FileTags) setAdvisory(advisory int) {
+	ft.Explicit = advisory == 1 || advisory == 4
+	ft.Clean = advisory == 2
+}
+
+////////////
+// MP3 / ID3v2
+////////////
+
+// id3Frames maps ID3v2.3+ text frames onto tag names
+var id3Frames = map[string]string{
+	"TIT2": "title",
+	"TPE1": "artist",
+	"TPE2": "albumartist",
+	"TALB": "album",
+	"TCOM": "composer",
+	"TCON": "genre",
+	"TYER": "year",
+	"TDRC": "date",
+	"TRCK": "tracknumber",
+	"TPOS": "discnumber",
+	"TCMP": "compilation",
+	"TSRC": "isrc",
+	"TPE3": "conductor",
+	"MVNM": "movementname",
+	"MVIN": "movementnumber",
+}
+
+// id3v22Frames maps the three character frame ids of
+// ID3v2.2 onto their ID3v2.3+ equivalents
+var id3v22Frames = map[string]string{
+	"TT2": "TIT2",
+	"TP1": "TPE1",
+	"TP2": "TPE2",
+	"TP3": "TPE3",
+	"TAL": "TALB",
+	"TCM": "TCOM",
+	"TCO": "TCON",
+	"TYE": "TYER",
+	"TRK": "TRCK",
+	"TPA": "TPOS",
+	"TCP": "TCMP",
+	"TRC": "TSRC",
+	"TLE": "TLEN",
+	"TXX": "TXXX",
+	"UFI": "UFID",
+}
+
+func readMP3Tags(f *os.File, tags *FileTags) error {
+
+	size, err := readID3Tags(f, tags)
+	if err == errNoID3 {
+		size = 0
+	} else if nil != err {
+		return err
+	}
+
+	if tags.Duration == 0 {
+		tags.Duration = mp3Duration(f, size)
+	}
+	return nil
+
+}
+
+// readID3Tags reads an ID3v2 tag from the start of r,
+// returning the total size of the tag in bytes
+func readID3Tags(r io.Reader, tags *FileTags) (int64, error) {
+
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); nil != err {
+		return 0, err
+	}
+	if string(header[0:3]) != "ID3" {
+		return 0, errNoID3
+	}
+
+	version := header[3]
+	flags := header[5]
+	size := syncsafeInt(header[6:10])
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); nil != err {
+		return 0, err
+	}
+
+	total := int64(10 + size)
+	if flags&0x10 != 0 {
+		// v2.4 footer
+		total += 10
+	}
+
+	// whole tag unsynchronisation was replaced by
+	// per frame unsynchronisation in v2.4
+	if flags&0x80 != 0 && version < 4 {
+		data = bytes.Replace(data, []byte{0xFF, 0x00}, []byte{0xFF}, -1)
+	}
+
+	if flags&0x40 != 0 && version > 2 && len(data) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(data[0:4]))
+		if version == 3 {
+			extSize += 4
+		} else {
+			extSize = syncsafeInt(data[0:4])
+		}
+		if extSize > len(data) {
+			return 0, fmt.Errorf("invalid ID3v2 extended header")
+		}
+		data = data[extSize:]
+	}
+
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	for len(data) >= headerLen && data[0] != 0 {
+
+		id := string(data[0:idLen])
+		var frameSize int
+		switch version {
+		case 2:
+			frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+		default:
+			frameSize = syncsafeInt(data[4:8])
+		}
+
+		if frameSize > len(data)-headerLen {
+			break
+		}
+		frame := data[headerLen : headerLen+frameSize]
+		data = data[headerLen+frameSize:]
+
+		if version == 2 {
+			id = id3v22Frames[id]
+		}
+		applyID3Frame(id, frame, tags)
+
+	}
+
+	return total, nil
+
+}
+
+func applyID3Frame(id string, frame []byte, tags *FileTags) {
+
+	if len(frame) == 0 {
+		return
+	}
+
+	switch id {
+
+	case "UFID":
+		// owner identifier, null, then binary identifier
+		parts := bytes.SplitN(frame, []byte{0}, 2)
+		if len(parts) == 2 && string(parts[0]) == "http://musicbrainz.org" {
+			tags.set("musicbrainztrackid", string(parts[1]))
+		}
+
+	case "TXXX":
+		// user defined text: description, null, value
+		values := decodeID3Text(frame[0], frame[1:])
+		if len(values) >= 2 {
+			tags.set(values[0], values[1])
+		}
+
+	case "TLEN":
+		values := decodeID3Text(frame[0], frame[1:])
+		if len(values) > 0 {
+			tags.Duration = leadingInt(values[0])
+		}
+
+	default:
+		name, ok := id3Frames[id]
+		if !ok {
+			return
+		}
+		for _, value := range decodeID3Text(frame[0], frame[1:]) {
+			tags.set(name, value)
+			if name != "artist" {
+				break
+			}
+		}
+
+	}
+
+}
+
+// decodeID3Text decodes the given text frame data with the
+// specified ID3 encoding, returning all non-empty values
+func decodeID3Text(encoding byte, data []byte) []string {
+
+	var text string
+	switch encoding {
+
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+			order = binary.LittleEndian
+		}
+		if len(data) >= 2 && (data[0] == 0xFF && data[1] == 0xFE || data[0] == 0xFE && data[1] == 0xFF) {
+			data = data[2:]
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[i*2:])
+		}
+		text = string(utf16.Decode(units))
+
+	case 3:
+		text = string(data)
+
+	default:
+		text = latin1String(data)
+
+	}
+
+	var values []string
+	for _, value := range strings.Split(text, "\x00") {
+		// BOMs may be repeated for each value in v2.4
+		value = strings.TrimSpace(strings.TrimPrefix(value, "\ufeff"))
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+
+}
+
+// mpeg1Bitrates and mpeg2Bitrates are the layer III
+// bitrates in kbps, indexed by the frame header bitrate bits
+var mpeg1Bitrates = []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+var mpeg2Bitrates = []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+
+// mp3Duration estimates the duration of an mp3 file in milliseconds
+// from the first frame found after offset, using the Xing/Info frame
+// count if available and assuming a constant bitrate otherwise
+func mp3Duration(f *os.File, offset int64) int {
+
+	stat, err := f.Stat()
+	if nil != err {
+		return 0
+	}
+
+	buf := make([]byte, 8192)
+	n, err := f.ReadAt(buf, offset)
+	if n < 4 {
+		return 0
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+
+		if buf[i] != 0xFF || buf[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+
+		versionBits := buf[i+1] >> 3 & 0x03
+		layerBits := buf[i+1] >> 1 & 0x03
+		bitrateIndex := int(buf[i+2] >> 4)
+		rateIndex := int(buf[i+2] >> 2 & 0x03)
+		mono := buf[i+3]>>6 == 0x03
+
+		// only layer III and valid header values
+		if layerBits != 0x01 || versionBits == 0x01 ||
+			bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+			continue
+		}
+
+		sampleRate := []int{44100, 48000, 32000}[rateIndex]
+		bitrate := mpeg1Bitrates[bitrateIndex]
+		samples := 1152
+		sideInfo := 32
+		if mono {
+			sideInfo = 17
+		}
+		if versionBits != 0x03 {
+			// MPEG 2 and 2.5
+			sampleRate /= 2
+			if versionBits == 0x00 {
+				sampleRate /= 2
+			}
+			bitrate = mpeg2Bitrates[bitrateIndex]
+			samples = 576
+			sideInfo = 17
+			if mono {
+				sideInfo = 9
+			}
+		}
+
+		xing := i + 4 + sideInfo
+		if xing+12 <= len(buf) {
+			tag := string(buf[xing : xing+4])
+			if (tag == "Xing" || tag == "Info") && buf[xing+7]&0x01 != 0 {
+				frames := int(binary.BigEndian.Uint32(buf[xing+8 : xing+12]))
+				return int(int64(frames) * int64(samples) * 1000 / int64(sampleRate))
+			}
+		}
+
+		audioBytes := stat.Size() - offset - int64(i)
+		return int(audioBytes * 8 / int64(bitrate))
+
+	}
+
+	return 0
+
+}
+
+////////////
+// FLAC / Ogg vorbis comments
+////////////
+
+func readFLACTags(f *os.File, tags *FileTags) error {
+
+	r := io.Reader(f)
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); nil != err {
+		return err
+	}
+
+	// some taggers put an ID3 tag in front of the stream
+	if string(magic[0:3]) == "ID3" {
+		if _, err := f.Seek(0, io.SeekStart); nil != err {
+			return err
+		}
+		if _, err := readID3Tags(f, &FileTags{}); nil != err {
+			return err
+		}
+		if _, err := io.ReadFull(r, magic); nil != err {
+			return err
+		}
+	}
+	if string(magic) != "fLaC" {
+		return fmt.Errorf("not a flac file")
+	}
+
+	header := make([]byte, 4)
+	for {
+
+		if _, err := io.ReadFull(r, header); nil != err {
+			return err
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); nil != err {
+			return err
+		}
+
+		switch blockType {
+		case 0:
+			// STREAMINFO: 20 bits sample rate and 36 bits total samples
+			if len(block) >= 18 {
+				v := binary.BigEndian.Uint64(block[10:18])
+				sampleRate := v >> 44
+				total := v & 0xFFFFFFFFF
+				if sampleRate > 0 {
+					tags.Duration = int(total * 1000 / sampleRate)
+				}
+			}
+		case 4:
+			readVorbisComments(block, tags)
+		}
+
+		if last {
+			return nil
+		}
+
+	}
+
+}
+
+// readVorbisComments reads a vorbis comment block (without framing)
+func readVorbisComments(data []byte, tags *FileTags) {
+
+	next := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(data[0:4]))
+		if n > len(data)-4 {
+			return "", false
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, true
+	}
+
+	// vendor string
+	if _, ok := next(); !ok || len(data) < 4 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(data[0:4]))
+	data = data[4:]
+
+	for i := 0; i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return
+		}
+		parts := strings.SplitN(comment, "=", 2)
+		if len(parts) == 2 {
+			tags.set(parts[0], parts[1])
+		}
+	}
+
+}
+
+func readOggTags(f *os.File, tags *FileTags) error {
+
+	r := io.Reader(f)
+	header := make([]byte, 27)
+	sampleRate := uint64(0)
+
+	var packet []byte
+	packets := 0
+	for packets < 2 {
+
+		if _, err := io.ReadFull(r, header); nil != err {
+			return err
+		}
+		if string(header[0:4]) != "OggS" {
+			return fmt.Errorf("invalid ogg page")
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); nil != err {
+			return err
+		}
+
+		for _, segment := range segments {
+
+			data := make([]byte, segment)
+			if _, err := io.ReadFull(r, data); nil != err {
+				return err
+			}
+			packet = append(packet, data...)
+			if segment == 255 {
+				continue
+			}
+
+			// the first packet identifies the codec,
+			// the second holds the comments
+			switch {
+			case bytes.HasPrefix(packet, []byte("\x01vorbis")) && len(packet) >= 16:
+				sampleRate = uint64(binary.LittleEndian.Uint32(packet[12:16]))
+			case bytes.HasPrefix(packet, []byte("OpusHead")):
+				sampleRate = 48000
+			case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+				readVorbisComments(packet[7:], tags)
+			case bytes.HasPrefix(packet, []byte("OpusTags")):
+				readVorbisComments(packet[8:], tags)
+			}
+
+			packet = nil
+			packets++
+			if packets == 2 {
+				break
+			}
+
+		}
+
+	}
+
+	// the granule position of the last page is the total sample count
+	stat, err := f.Stat()
+	if nil != err || sampleRate == 0 {
+		return nil
+	}
+	start := stat.Size() - 65536
+	if start < 0 {
+		start = 0
+	}
+	tail := make([]byte, stat.Size()-start)
+	if _, err := f.ReadAt(tail, start); nil != err && err != io.EOF {
+		return nil
+	}
+	if last := bytes.LastIndex(tail, []byte("OggS")); last >= 0 && last+14 <= len(tail) {
+		granule := binary.LittleEndian.Uint64(tail[last+6 : last+14])
+		tags.Duration = int(granule * 1000 / sampleRate)
+	}
+
+	return nil
+
+}
+
+////////////
+// MP4 atoms
+////////////
+
+// mp4Items maps the metadata item atoms onto tag names
+var mp4Items = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "albumartist",
+	"\xa9alb": "album",
+	"\xa9wrt": "composer",
+	"\xa9gen": "genre",
+	"\xa9day": "date",
+	"\xa9wrk": "work",
+	"\xa9mvn": "movementname",
+}
+
+// maxMP4Metadata is the largest moov atom that is read, which holds
+// the sample tables as well as the tags but is rarely over a few MB
+const maxMP4Metadata = 64 << 20
+
+type mp4Atom struct {
+	kind string
+	data []byte
+}
+
+// mp4Atoms splits data into the atoms that it contains
+func mp4Atoms(data []byte) []mp4Atom {
+
+	var atoms []mp4Atom
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		kind := string(data[4:8])
+		headerLen := uint64(8)
+		if size == 1 && len(data) >= 16 {
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerLen = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			break
+		}
+		atoms = append(atoms, mp4Atom{kind, data[headerLen:size]})
+		data = data[size:]
+	}
+	return atoms
+
+}
+
+func readMP4Tags(f *os.File, tags *FileTags) error {
+
+	stat, err := f.Stat()
+	if nil != err {
+		return err
+	}
+
+	// the moov atom is usually small but can be anywhere in the
+	// file so walk the top level headers without reading media data
+	header := make([]byte, 16)
+	offset := int64(0)
+	var moov []byte
+	for moov == nil {
+
+		if _, err := f.ReadAt(header[:8], offset); nil != err {
+			return fmt.Errorf("no mp4 metadata found")
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		kind := string(header[4:8])
+		headerLen := int64(8)
+		if size == 1 {
+			if _, err := f.ReadAt(header[8:16], offset+8); nil != err {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || size > stat.Size()-offset {
+			return fmt.Errorf("invalid mp4 atom: %s", kind)
+		}
+
+		if kind == "moov" {
+			if size-headerLen > maxMP4Metadata {
+				return fmt.Errorf("mp4 metadata too large: %d bytes", size)
+			}
+			moov = make([]byte, size-headerLen)
+			if _, err := f.ReadAt(moov, offset+headerLen); nil != err {
+				return err
+			}
+		}
+		offset += size
+
+	}
+
+	for _, atom := range mp4Atoms(moov) {
+		switch atom.kind {
+		case "mvhd":
+			readMP4Duration(atom.data, tags)
+		case "udta":
+			for _, meta := range mp4Atoms(atom.data) {
+				// meta has a version and flags before its children
+				if meta.kind != "meta" || len(meta.data) < 4 {
+					continue
+				}
+				for _, ilst := range mp4Atoms(meta.data[4:]) {
+					if ilst.kind == "ilst" {
+						readMP4Items(ilst.data, tags)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+
+}
+
+func readMP4Duration(mvhd []byte, tags *FileTags) {
+
+	if len(mvhd) < 20 {
+		return
+	}
+	var timescale, duration uint64
+	if mvhd[0] == 1 && len(mvhd) >= 32 {
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:24]))
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	}
+	if timescale > 0 {
+		tags.Duration = int(duration * 1000 / timescale)
+	}
+
+}
+
+func readMP4Items(ilst []byte, tags *FileTags) {
+
+	for _, item := range mp4Atoms(ilst) {
+
+		var name string
+		var value []byte
+		for _, child := range mp4Atoms(item.data) {
+			// mean and name atoms have version and flags
+			// data atoms have a type and locale
+			if len(child.data) < 4 {
+				continue
+			}
+			switch child.kind {
+			case "name":
+				name = string(child.data[4:])
+			case "data":
+				if len(child.data) >= 8 {
+					value = child.data[8:]
+				}
+			}
+		}
+		if value == nil {
+			continue
+		}
+
+		switch item.kind {
+		case "----":
+			tags.set(name, string(value))
+		case "trkn", "disk":
+			// padding, then 16 bit number and total
+			if len(value) >= 4 {
+				n := int(binary.BigEndian.Uint16(value[2:4]))
+				if item.kind == "trkn" {
+					tags.TrackNumber = n
+				} else {
+					tags.DiscNumber = n
+				}
+			}
+		case "\xa9mvi":
+			// 16 bit movement number
+			if len(value) >= 2 {
+				tags.MovementNumber = int(binary.BigEndian.Uint16(value[:2]))
+			}
+		case "rtng":
+			if len(value) > 0 {
+				tags.setAdvisory(int(value[0]))
+			}
+		case "cpil":
+			tags.Compilation = len(value) > 0 && value[0] != 0
+		default:
+			if field, ok := mp4Items[item.kind]; ok {
+				tags.set(field, string(value))
+			}
+		}
+
+	}
+
+}
+
+////////////
+// helpers
+////////////
+
+func syncsafeInt(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n = n<<7 | int(c&0x7F)
+	}
+	return n
+}
+
+// leadingInt parses the number at the start of values
+// like "3/12", returning 0 if there is none
+func leadingInt(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+func latin1String(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
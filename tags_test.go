@@ -0,0 +1,526 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// id3Tag builds an ID3v2 tag of the given version around the frames
+func id3Tag(version byte, frames ...[]byte) []byte {
+	data := bytes.Join(frames, nil)
+	size := len(data)
+	header := []byte{'I', 'D', '3', version, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(header, data...)
+}
+
+// id3Frame builds a frame of the given version, sizing it by the data
+func id3Frame(version byte, id string, data string) []byte {
+	size := len(data)
+	var header []byte
+	switch version {
+	case 2:
+		header = append([]byte(id), byte(size>>16), byte(size>>8), byte(size))
+	case 3:
+		header = append([]byte(id), byte(size>>24), byte(size>>16), byte(size>>8), byte(size), 0, 0)
+	default:
+		header = append([]byte(id), byte(size>>21&0x7F), byte(size>>14&0x7F), byte(size>>7&0x7F), byte(size&0x7F), 0, 0)
+	}
+	return append(header, data...)
+}
+
+func TestReadID3Tags(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		tag      []byte
+		expected FileTags
+	}{
+		{
+			"v2.3 text frames",
+			id3Tag(3,
+				id3Frame(3, "TIT2", "\x00Caf\xe9"),
+				id3Frame(3, "TPE1", "\x03First\x00Second"),
+				id3Frame(3, "TALB", "\x01\xff\xfeA\x00l\x00b\x00"),
+				id3Frame(3, "TRCK", "\x003/12"),
+				id3Frame(3, "TYER", "\x001999"),
+				id3Frame(3, "TLEN", "\x00215000"),
+				id3Frame(3, "TXXX", "\x00MusicBrainz Album Id\x00album-id"),
+				id3Frame(3, "UFID", "http://musicbrainz.org\x00track-id"),
+				id3Frame(3, "TXXX", "\x00ITUNESADVISORY\x001"),
+			),
+			FileTags{Title: "Café", Artist: "First & Second", Album: "Alb", TrackNumber: 3, Year: 1999,
+				Duration: 215000, MusicBrainzAlbumID: "album-id", MusicBrainzTrackID: "track-id", Explicit: true},
+		},
+		{
+			"v2.2 frames",
+			id3Tag(2,
+				id3Frame(2, "TT2", "\x00Title"),
+				id3Frame(2, "TP1", "\x00Artist"),
+				id3Frame(2, "TPA", "\x002/2"),
+			),
+			FileTags{Title: "Title", Artist: "Artist", DiscNumber: 2},
+		},
+		{
+			"v2.4 syncsafe sizes",
+			id3Tag(4,
+				id3Frame(4, "TIT2", "\x03"+string(bytes.Repeat([]byte("a"), 200))),
+				id3Frame(4, "TCON", "\x02\x00R\x00o\x00c\x00k"),
+			),
+			FileTags{Title: string(bytes.Repeat([]byte("a"), 200)), Genre: "Rock"},
+		},
+		{
+			"truncated frame",
+			id3Tag(3,
+				id3Frame(3, "TIT2", "\x00Title"),
+				id3Frame(3, "TPE1", "\x00Artist")[:14],
+			),
+			FileTags{Title: "Title"},
+		},
+		{
+			"empty and unknown frames",
+			id3Tag(3,
+				id3Frame(3, "TIT2", ""),
+				id3Frame(3, "XXXX", "\x00value"),
+				id3Frame(3, "TXXX", "\x00no value"),
+				id3Frame(3, "TALB", "\x00Album"),
+			),
+			FileTags{Album: "Album"},
+		},
+		{
+			"padding",
+			id3Tag(3,
+				id3Frame(3, "TIT2", "\x00Title"),
+				make([]byte, 20),
+				id3Frame(3, "TALB", "\x00Hidden"),
+			),
+			FileTags{Title: "Title"},
+		},
+	}
+
+	for _, test := range tests {
+		var tags FileTags
+		size, err := readID3Tags(bytes.NewReader(test.tag), &tags)
+		if nil != err {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if size != int64(len(test.tag)) {
+			t.Errorf("%s: expected size %d, got %d", test.name, len(test.tag), size)
+		}
+		if tags != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, tags)
+		}
+	}
+
+	if _, err := readID3Tags(bytes.NewReader([]byte("fLaC\x00\x00\x00\x00\x00\x00")), &FileTags{}); err != errNoID3 {
+		t.Errorf("expected errNoID3 without an ID3 header, got %v", err)
+	}
+	tag := id3Tag(3, id3Frame(3, "TIT2", "\x00Title"))
+	if _, err := readID3Tags(bytes.NewReader(tag[:len(tag)-3]), &FileTags{}); nil == err {
+		t.Errorf("expected an error for a truncated tag")
+	}
+
+}
+
+func TestDecodeID3Text(t *testing.T) {
+
+	tests := []struct {
+		encoding byte
+		data     string
+		expected []string
+	}{
+		{0, "Caf\xe9", []string{"Café"}},
+		{0, "", nil},
+		{1, "\xff\xfeA\x00\x00\x00\xff\xfeB\x00", []string{"A", "B"}},
+		{1, "\xfe\xff\x00A", []string{"A"}},
+		{1, "\xff\xfeA", nil}, // odd trailing byte
+		{2, "\x00A\x00B", []string{"AB"}},
+		{3, "One\x00\x00 Two ", []string{"One", "Two"}},
+	}
+
+	for _, test := range tests {
+		values := decodeID3Text(test.encoding, []byte(test.data))
+		if len(values) != len(test.expected) {
+			t.Errorf("expected %q for %q, got %q", test.expected, test.data, values)
+			continue
+		}
+		for i := range values {
+			if values[i] != test.expected[i] {
+				t.Errorf("expected %q for %q, got %q", test.expected, test.data, values)
+				break
+			}
+		}
+	}
+
+}
+
+// vorbisComments builds a vorbis comment block
+func vorbisComments(vendor string, comments ...string) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint32(len(vendor)))
+	b.WriteString(vendor)
+	binary.Write(&b, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		binary.Write(&b, binary.LittleEndian, uint32(len(c)))
+		b.WriteString(c)
+	}
+	return b.Bytes()
+}
+
+func TestReadVorbisComments(t *testing.T) {
+
+	full := vorbisComments("vendor", "TITLE=Title", "ARTIST=One", "artist=Two", "ALBUM ARTIST=Various",
+		"TRACKNUMBER=4/10", "COMPILATION=1", "MOVEMENTNAME=Allegro", "MOVEMENT=II", "ISRC=usabc1234567", "broken")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected FileTags
+	}{
+		{"complete", full, FileTags{Title: "Title", Artist: "One & Two", AlbumArtist: "Various",
+			TrackNumber: 4, Compilation: true, MovementName: "Allegro", ISRC: "USABC1234567"}},
+		{"truncated comment", full[:len(vorbisComments("vendor", "TITLE=Title", "ARTIST=One"))+6],
+			FileTags{Title: "Title", Artist: "One"}},
+		{"truncated count", vorbisComments("vendor")[:12], FileTags{}},
+		{"truncated vendor", vorbisComments("vendor")[:6], FileTags{}},
+		{"count past the end", append(vorbisComments("v")[:5], 0xFF, 0xFF, 0xFF, 0x00), FileTags{}},
+	}
+
+	for _, test := range tests {
+		var tags FileTags
+		readVorbisComments(test.data, &tags)
+		if tags != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, tags)
+		}
+	}
+
+}
+
+// writeTestFile writes data into a temporary file with the given
+// name, returning its path and a function to remove it
+func writeTestFile(t *testing.T, name string, data []byte) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "itsp")
+	if nil != err {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err = ioutil.WriteFile(path, data, 0644); nil != err {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+
+}
+
+// flacBlock builds a metadata block header around data
+func flacBlock(blockType byte, last bool, data []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	size := len(data)
+	return append([]byte{blockType, byte(size >> 16), byte(size >> 8), byte(size)}, data...)
+}
+
+func TestReadFLACTags(t *testing.T) {
+
+	// 44.1kHz with two seconds of samples
+	streamInfo := make([]byte, 34)
+	binary.BigEndian.PutUint64(streamInfo[10:18], 44100<<44|88200)
+	comments := vorbisComments("vendor", "TITLE=Title", "DATE=2001-02-03")
+
+	flac := append([]byte("fLaC"), flacBlock(0, false, streamInfo)...)
+	flac = append(flac, flacBlock(4, true, comments)...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *FileTags
+	}{
+		{"flac", flac, &FileTags{Title: "Title", Year: 2001, Duration: 2000}},
+		{"id3 prefix", append(id3Tag(3, id3Frame(3, "TIT2", "\x00Ignored")), flac...),
+			&FileTags{Title: "Title", Year: 2001, Duration: 2000}},
+		{"truncated block", flac[:len(flac)-4], nil},
+		{"no last block", flac[:len(flac)-len(comments)-4], nil},
+		{"not flac", []byte("OggS\x00\x00\x00\x00"), nil},
+	}
+
+	for _, test := range tests {
+		path, remove := writeTestFile(t, "test.flac", test.data)
+		tags, err := ReadFileTags(path)
+		remove()
+		if test.expected == nil {
+			if nil == err {
+				t.Errorf("%s: expected an error, got %+v", test.name, tags)
+			}
+			continue
+		}
+		if nil != err {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		} else if *tags != *test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, *test.expected, *tags)
+		}
+	}
+
+}
+
+// oggPage builds an ogg page holding the given packets,
+// each of which must be shorter than a segment
+func oggPage(granule uint64, packets ...[]byte) []byte {
+	header := make([]byte, 27)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], granule)
+	header[26] = byte(len(packets))
+	for _, p := range packets {
+		header = append(header, byte(len(p)))
+	}
+	return append(header, bytes.Join(packets, nil)...)
+}
+
+func TestReadOggTags(t *testing.T) {
+
+	ident := make([]byte, 30)
+	copy(ident, "\x01vorbis")
+	binary.LittleEndian.PutUint32(ident[12:16], 44100)
+	comments := append([]byte("\x03vorbis"), vorbisComments("vendor", "TITLE=Title", "GENRE=Jazz")...)
+	opusComments := append([]byte("OpusTags"), vorbisComments("vendor", "TITLE=Opus")...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *FileTags
+	}{
+		{"vorbis", oggPage(44100*3, ident, comments), &FileTags{Title: "Title", Genre: "Jazz", Duration: 3000}},
+		{"vorbis over pages", append(oggPage(0, ident), oggPage(22050, comments)...),
+			&FileTags{Title: "Title", Genre: "Jazz", Duration: 500}},
+		{"opus", oggPage(48000, []byte("OpusHead\x01\x02"), opusComments), &FileTags{Title: "Opus", Duration: 1000}},
+		{"truncated packet", oggPage(0, ident, comments)[:40], nil},
+		{"not ogg", append([]byte("fLaC"), make([]byte, 30)...), nil},
+	}
+
+	for _, test := range tests {
+		path, remove := writeTestFile(t, "test.ogg", test.data)
+		tags, err := ReadFileTags(path)
+		remove()
+		if test.expected == nil {
+			if nil == err {
+				t.Errorf("%s: expected an error, got %+v", test.name, tags)
+			}
+			continue
+		}
+		if nil != err {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		} else if *tags != *test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, *test.expected, *tags)
+		}
+	}
+
+}
+
+// mp4AtomBytes builds an atom of the given kind around its children
+func mp4AtomBytes(kind string, children ...[]byte) []byte {
+	data := bytes.Join(children, nil)
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(8+len(data)))
+	copy(header[4:8], kind)
+	return append(header, data...)
+}
+
+// mp4Item builds a metadata item with a single data atom
+func mp4Item(kind string, value []byte) []byte {
+	return mp4AtomBytes(kind, mp4AtomBytes("data", make([]byte, 8), value))
+}
+
+func TestMP4Atoms(t *testing.T) {
+
+	data := append(mp4AtomBytes("free", []byte("abc")), mp4AtomBytes("skip")...)
+
+	large := make([]byte, 16)
+	binary.BigEndian.PutUint32(large[0:4], 1)
+	copy(large[4:8], "wide")
+	binary.BigEndian.PutUint64(large[8:16], 20)
+	large = append(large, "data"...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []mp4Atom
+	}{
+		{"atoms", data, []mp4Atom{{"free", []byte("abc")}, {"skip", []byte{}}}},
+		{"64 bit size", large, []mp4Atom{{"wide", []byte("data")}}},
+		{"to the end", []byte("\x00\x00\x00\x00mdatxy"), []mp4Atom{{"mdat", []byte("xy")}}},
+		{"truncated", data[:len(data)-1], []mp4Atom{{"free", []byte("abc")}}},
+		{"undersized", []byte("\x00\x00\x00\x04free"), nil},
+		{"short header", []byte("\x00\x00\x00"), nil},
+	}
+
+	for _, test := range tests {
+		atoms := mp4Atoms(test.data)
+		if len(atoms) != len(test.expected) {
+			t.Errorf("%s: expected %d atoms, got %d", test.name, len(test.expected), len(atoms))
+			continue
+		}
+		for i, a := range test.expected {
+			if atoms[i].kind != a.kind || !bytes.Equal(atoms[i].data, a.data) {
+				t.Errorf("%s: expected atom %d to be %q, got %q", test.name, i, a, atoms[i])
+			}
+		}
+	}
+
+}
+
+func TestReadMP4Items(t *testing.T) {
+
+	freeform := mp4AtomBytes("----",
+		mp4AtomBytes("mean", []byte("\x00\x00\x00\x00com.apple.iTunes")),
+		mp4AtomBytes("name", []byte("\x00\x00\x00\x00MusicBrainz Track Id")),
+		mp4AtomBytes("data", make([]byte, 8), []byte("track-id")))
+
+	tests := []struct {
+		name     string
+		ilst     []byte
+		expected FileTags
+	}{
+		{
+			"items",
+			bytes.Join([][]byte{
+				mp4Item("\xa9nam", []byte("Title")),
+				mp4Item("\xa9ART", []byte("Artist")),
+				mp4Item("\xa9day", []byte("2004-05-06T00:00:00Z")),
+				mp4Item("trkn", []byte{0, 0, 0, 7, 0, 12, 0, 0}),
+				mp4Item("disk", []byte{0, 0, 0, 2, 0, 2}),
+				mp4Item("\xa9mvi", []byte{0, 3}),
+				mp4Item("cpil", []byte{1}),
+				mp4Item("rtng", []byte{2}),
+				freeform,
+			}, nil),
+			FileTags{Title: "Title", Artist: "Artist", Year: 2004, TrackNumber: 7, DiscNumber: 2,
+				MovementNumber: 3, Compilation: true, Clean: true, MusicBrainzTrackID: "track-id"},
+		},
+		{
+			"short values",
+			bytes.Join([][]byte{
+				mp4Item("trkn", []byte{0, 0}),
+				mp4Item("\xa9mvi", []byte{3}),
+				mp4Item("rtng", nil),
+				mp4AtomBytes("\xa9alb", mp4AtomBytes("data", []byte{0, 0, 0, 1})),
+				mp4Item("xxxx", []byte("unknown")),
+			}, nil),
+			FileTags{},
+		},
+		{
+			"truncated item",
+			append(mp4Item("\xa9nam", []byte("Title")), mp4Item("\xa9alb", []byte("Album"))[:20]...),
+			FileTags{Title: "Title"},
+		},
+	}
+
+	for _, test := range tests {
+		var tags FileTags
+		readMP4Items(test.ilst, &tags)
+		if tags != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, tags)
+		}
+	}
+
+}
+
+func TestReadMP4Tags(t *testing.T) {
+
+	// version 0 movie header, 1000 per second for 4.5 seconds
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 4500)
+
+	moov := mp4AtomBytes("moov",
+		mp4AtomBytes("mvhd", mvhd),
+		mp4AtomBytes("udta",
+			mp4AtomBytes("meta", make([]byte, 4),
+				mp4AtomBytes("hdlr", make([]byte, 25)),
+				mp4AtomBytes("ilst", mp4Item("\xa9nam", []byte("Title"))))))
+	ftyp := mp4AtomBytes("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	mdat := mp4AtomBytes("mdat", make([]byte, 64))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *FileTags
+	}{
+		{"moov first", bytes.Join([][]byte{ftyp, moov, mdat}, nil), &FileTags{Title: "Title", Duration: 4500}},
+		{"moov last", bytes.Join([][]byte{ftyp, mdat, moov}, nil), &FileTags{Title: "Title", Duration: 4500}},
+		{"no moov", bytes.Join([][]byte{ftyp, mdat}, nil), nil},
+		{"truncated moov", bytes.Join([][]byte{ftyp, moov[:len(moov)-10]}, nil), nil},
+		{"invalid atom", append(ftyp, "\x00\x00\x00\x02moov"...), nil},
+		{"atom past the end", bytes.Join([][]byte{ftyp, []byte("\x00\x00\x10\x00mdat"), moov}, nil), nil},
+		{"huge extended size", bytes.Join([][]byte{ftyp, []byte("\x00\x00\x00\x01moov\x7f\xff\xff\xff\xff\xff\xff\xff"), moov}, nil), nil},
+	}
+
+	for _, test := range tests {
+		path, remove := writeTestFile(t, "test.m4a", test.data)
+		tags, err := ReadFileTags(path)
+		remove()
+		if test.expected == nil {
+			if nil == err {
+				t.Errorf("%s: expected an error, got %+v", test.name, tags)
+			}
+			continue
+		}
+		if nil != err {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		} else if *tags != *test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, *test.expected, *tags)
+		}
+	}
+
+}
+
+func TestReadMP4Duration(t *testing.T) {
+
+	v1 := make([]byte, 32)
+	v1[0] = 1
+	binary.BigEndian.PutUint32(v1[20:24], 600)
+	binary.BigEndian.PutUint64(v1[24:32], 1<<33)
+
+	zero := make([]byte, 20)
+
+	tests := []struct {
+		mvhd     []byte
+		expected int
+	}{
+		{v1, int((1 << 33) * 1000 / 600)},
+		{v1[:24], 0}, // falls back to version 0 fields
+		{zero, 0},
+		{zero[:19], 0},
+	}
+
+	for i, test := range tests {
+		var tags FileTags
+		readMP4Duration(test.mvhd, &tags)
+		if tags.Duration != test.expected {
+			t.Errorf("expected duration %d for header %d, got %d", test.expected, i, tags.Duration)
+		}
+	}
+
+}
+
+func TestTagHelpers(t *testing.T) {
+
+	if n := syncsafeInt([]byte{0x00, 0x00, 0x02, 0x01}); n != 257 {
+		t.Errorf("expected syncsafe 257, got %d", n)
+	}
+	if n := syncsafeInt([]byte{0xFF, 0xFF}); n != 1<<14-1 {
+		t.Errorf("expected the high bits to be ignored, got %d", n)
+	}
+
+	for s, expected := range map[string]int{"3/12": 3, "2004-05": 2004, "": 0, "x1": 0, "07": 7} {
+		if n := leadingInt(s); n != expected {
+			t.Errorf("expected %d for %q, got %d", expected, s, n)
+		}
+	}
+
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nsf/termbox-go"
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// ReviewAction is what the user decided to do with a track
+// on the review screen
+type ReviewAction int
+
+// all of the possible review actions
+const (
+	ReviewSelect ReviewAction = iota
+	ReviewSearch
+	ReviewSkip
+	ReviewUnavailable
+)
+
+// ReviewResult is the outcome of reviewing a single track
+type ReviewResult struct {
+	Action     ReviewAction
+	Match      *MatchedTrack
+	Query      string
+	WholeAlbum bool
+}
+
+// reviewScreen is a full screen terminal view that shows a library
+// track side by side with its spotify candidates to choose between
+type reviewScreen struct {
+	goal       *itunes.Track
+	candidates []*MatchedTrack
+	years      map[*MatchedTrack]string
+
+	selected  int
+	scroll    int
+	searching bool
+	query     []rune
+	width     int
+	height    int
+}
+
+const reviewHelp = "↑/↓ move  enter select  a select for whole album  / search  s skip  u unavailable"
+
+// ReviewMatch shows the review screen for the given track and candidates
+// until the user chooses what to do with it, returning an error if the
+// screen cannot be shown (eg: there is no terminal)
+func ReviewMatch(goal *itunes.Track, candidates []*MatchedTrack) (ReviewResult, error) {
+
+	// the albums are fetched before the screen takes over the
+	// terminal, since fetching them can print retry messages
+	rs := &reviewScreen{
+		goal:       goal,
+		candidates: candidates,
+		years:      candidateYears(candidates),
+	}
+
+	if err := termbox.Init(); nil != err {
+		return ReviewResult{}, err
+	}
+	defer termbox.Close()
+
+	for {
+
+		rs.draw()
+
+		ev := termbox.PollEvent()
+		switch ev.Type {
+		case termbox.EventResize:
+			continue
+		case termbox.EventError:
+			return ReviewResult{Action: ReviewSkip}, nil
+		case termbox.EventKey:
+		default:
+			continue
+		}
+
+		if ev.Key == termbox.KeyCtrlC {
+			termbox.Close()
+			os.Exit(1)
+		}
+
+		if rs.searching {
+			switch ev.Key {
+			case termbox.KeyEnter:
+				if len(rs.query) > 0 {
+					return ReviewResult{Action: ReviewSearch, Query: string(rs.query)}, nil
+				}
+				rs.searching = false
+			case termbox.KeyEsc:
+				rs.searching = false
+				rs.query = nil
+			case termbox.KeyBackspace, termbox.KeyBackspace2:
+				if len(rs.query) > 0 {
+					rs.query = rs.query[:len(rs.query)-1]
+				}
+			case termbox.KeySpace:
+				rs.query = append(rs.query, ' ')
+			default:
+				if ev.Ch != 0 {
+					rs.query = append(rs.query, ev.Ch)
+				}
+			}
+			continue
+		}
+
+		switch {
+		case ev.Key == termbox.KeyArrowUp || ev.Ch == 'k':
+			rs.move(-1)
+		case ev.Key == termbox.KeyArrowDown || ev.Ch == 'j':
+			rs.move(1)
+		case ev.Key == termbox.KeyPgup:
+			rs.move(-rs.listHeight())
+		case ev.Key == termbox.KeyPgdn:
+			rs.move(rs.listHeight())
+		case ev.Key == termbox.KeyEnter && len(rs.candidates) > 0:
+			return ReviewResult{Action: ReviewSelect, Match: rs.candidates[rs.selected]}, nil
+		case ev.Ch == 'a' && len(rs.candidates) > 0:
+			return ReviewResult{Action: ReviewSelect, Match: rs.candidates[rs.selected], WholeAlbum: true}, nil
+		case ev.Ch == '/':
+			rs.searching = true
+			rs.query = nil
+		case ev.Ch == 's' || ev.Key == termbox.KeyEsc:
+			return ReviewResult{Action: ReviewSkip}, nil
+		case ev.Ch == 'u':
+			return ReviewResult{Action: ReviewUnavailable}, nil
+		}
+
+	}
+
+}
+
+func (rs *reviewScreen) move(delta int) {
+
+	rs.selected += delta
+	if rs.selected >= len(rs.candidates) {
+		rs.selected = len(rs.candidates) - 1
+	}
+	if rs.selected < 0 {
+		rs.selected = 0
+	}
+
+	if rs.selected < rs.scroll {
+		rs.scroll = rs.selected
+	}
+	if rs.selected >= rs.scroll+rs.listHeight() {
+		rs.scroll = rs.selected - rs.listHeight() + 1
+	}
+
+}
+
+// listHeight is the number of candidate rows that fit on screen
+// below the library track and above the candidate details
+func (rs *reviewScreen) listHeight() int {
+	if h := rs.height - 22; h > 1 {
+		return h
+	}
+	return 1
+}
+
+func (rs *reviewScreen) draw() {
+
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	rs.width, rs.height = termbox.Size()
+
+	bold := termbox.ColorDefault | termbox.AttrBold
+	goal := rs.goal
+
+	y := 0
+	rs.print(0, y, bold, "Library track")
+	y++
+	for _, line := range [][2]string{
+		{"title", goal.Name},
+		{"artist", goal.Artist},
+		{"album artist", goal.AlbumArtist},
+		{"album", goal.Album},
+		{"year", yearString(goal.Year)},
+		{"track", fmt.Sprintf("%d (disc %d)", goal.TrackNumber, goal.DiscNumber)},
+		{"duration", durationString(goal.TotalTime)},
+		{"genre", goal.Genre},
+	} {
+		rs.print(2, y, termbox.ColorDefault, fmt.Sprintf("%-13s %s", line[0], line[1]))
+		y++
+	}
+	y++
+
+	rs.print(0, y, bold, fmt.Sprintf("Spotify candidates (%d)", len(rs.candidates)))
+	y++
+	rs.print(2, y, termbox.ColorDefault|termbox.AttrUnderline, candidateRow(
+		"score", "title", "artists", "album", "type", "Δtime", "pop", "t/a/al"))
+	y++
+
+	if len(rs.candidates) == 0 {
+		rs.print(2, y, termbox.ColorYellow, "no candidates, press / to search")
+	}
+	for j := rs.scroll; j < len(rs.candidates) && j < rs.scroll+rs.listHeight(); j++ {
+		mt := rs.candidates[j]
+		fg, bg := termbox.ColorDefault, termbox.ColorDefault
+		if j == rs.selected {
+			fg, bg = termbox.ColorBlack, termbox.ColorCyan
+		}
+		row := candidateRow(
+			fmt.Sprintf("%1.4f", mt.score),
+			mt.spotify.Name,
+			artist(mt.spotify),
+			mt.spotify.Album.Name,
+			mt.spotify.Album.AlbumType,
+			durationDelta(goal, mt),
+			fmt.Sprintf("%d", mt.spotify.Popularity),
+			breakdownString(goal, mt),
+		)
+		rs.printBg(2, y, fg, bg, padRight(row, rs.width-2))
+		y++
+	}
+
+	// details of the selected candidate
+	y = rs.height - 11
+	if len(rs.candidates) > 0 {
+		mt := rs.candidates[rs.selected]
+		year, ok := rs.years[mt]
+		if !ok {
+			year = "?"
+		}
+		rs.print(0, y, bold, "Selected candidate")
+		y++
+		for _, line := range [][2]string{
+			{"title", mt.spotify.Name},
+			{"artists", artist(mt.spotify)},
+			{"album", fmt.Sprintf("%s (%s)", mt.spotify.Album.Name, mt.spotify.Album.AlbumType)},
+			{"year", year},
+			{"track", fmt.Sprintf("%d (disc %d)", mt.spotify.TrackNumber, mt.spotify.DiscNumber)},
+			{"duration", fmt.Sprintf("%s (%s)", durationString(mt.spotify.Duration),
+				durationDelta(goal, mt))},
+			{"popularity", fmt.Sprintf("%d", mt.spotify.Popularity)},
+			{"score", breakdownDetail(goal, mt)},
+		} {
+			rs.print(2, y, termbox.ColorDefault, fmt.Sprintf("%-13s %s", line[0], line[1]))
+			y++
+		}
+	}
+
+	if rs.searching {
+		rs.print(0, rs.height-1, termbox.ColorYellow, "search: "+string(rs.query)+"_")
+	} else {
+		rs.print(0, rs.height-1, termbox.ColorGreen, reviewHelp)
+	}
+
+	termbox.Flush()
+
+}
+
+// albumsPerRequest is the most albums that spotify returns at once
+const albumsPerRequest = 20
+
+// candidateYears fetches the release year of the album of each
+// candidate, fetching the distinct albums in as few requests as possible
+func candidateYears(candidates []*MatchedTrack) map[*MatchedTrack]string {
+
+	albums := make(map[spotify.ID]*spotify.FullAlbum)
+	var ids []spotify.ID
+	for _, mt := range candidates {
+		if !mt.Valid() {
+			continue
+		}
+		id := mt.spotify.Album.ID
+		if _, ok := albums[id]; !ok {
+			albums[id] = mt.sAlbum
+			if nil == mt.sAlbum {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	for start := 0; start < len(ids); start += albumsPerRequest {
+		end := start + albumsPerRequest
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var res []*spotify.FullAlbum
+		var err error
+		for {
+			res, err = Session.Client().GetAlbums(ids[start:end]...)
+			if Session.ShouldTryAgain(err) {
+				continue
+			}
+			break
+		}
+		if nil != err {
+			// the years are only shown, so go without the rest
+			break
+		}
+		for _, album := range res {
+			if nil != album {
+				albums[album.ID] = album
+			}
+		}
+	}
+
+	years := make(map[*MatchedTrack]string)
+	for _, mt := range candidates {
+		if !mt.Valid() {
+			continue
+		}
+		album := albums[mt.spotify.Album.ID]
+		if nil == album {
+			continue
+		}
+		// keep the album so that choosing this candidate does not fetch it again
+		mt.sAlbum = album
+		if len(album.ReleaseDate) >= 4 {
+			years[mt] = album.ReleaseDate[:4]
+		}
+	}
+	return years
+
+}
+
+func (rs *reviewScreen) print(x, y int, fg termbox.Attribute, text string) {
+	rs.printBg(x, y, fg, termbox.ColorDefault, text)
+}
+
+func (rs *reviewScreen) printBg(x, y int, fg, bg termbox.Attribute, text string) {
+	if y < 0 || y >= rs.height {
+		return
+	}
+	for _, r := range text {
+		if x >= rs.width {
+			return
+		}
+		termbox.SetCell(x, y, r, fg, bg)
+		x++
+	}
+}
+
+// candidateRow lays out the columns of a single candidate
+func candidateRow(score, title, artists, album, kind, delta, pop, breakdown string) string {
+	return fmt.Sprintf("%-7s %-30s %-24s %-24s %-11s %7s %4s  %s",
+		score, truncate(title, 30), truncate(artists, 24), truncate(album, 24),
+		truncate(kind, 11), delta, pop, breakdown)
+}
+
+// breakdownString shows the title, artist and album parts of a score
+func breakdownString(goal *itunes.Track, mt *MatchedTrack) string {
+	sb := mt.breakdown
+	if nil == sb {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f/%.2f/%.2f", sb.Title, sb.Artist, sb.Album)
+}
+
+// breakdownDetail explains the full score of a candidate
+func breakdownDetail(goal *itunes.Track, mt *MatchedTrack) string {
+	if nil == mt.breakdown {
+		return fmt.Sprintf("%1.4f", mt.score)
+	}
+	return fmt.Sprintf("%1.4f = %s", mt.score, mt.breakdown)
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+func padRight(s string, n int) string {
+	if c := utf8.RuneCountInString(s); c < n {
+		return s + strings.Repeat(" ", n-c)
+	}
+	return s
+}
+
+func yearString(year int) string {
+	if year == 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%d", year)
+}
+
+// durationString formats milliseconds as m:ss
+func durationString(ms int) string {
+	if ms <= 0 {
+		return "?"
+	}
+	s := ms / 1000
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// durationDelta formats how much longer the candidate is than the
+// library track in seconds, if the library track has a duration
+func durationDelta(goal *itunes.Track, mt *MatchedTrack) string {
+	if goal.TotalTime <= 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%+ds", (mt.spotify.Duration-goal.TotalTime)/1000)
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/zmb3/spotify"
 )
@@ -28,6 +33,46 @@ func RandomToken() string {
 	return string(b)
 }
 
+// fileURLToPath converts a file:// url (as used for locations in itunes
+// and many playlist formats) into a local path, leaving other values as is
+func fileURLToPath(location string) string {
+
+	if !strings.HasPrefix(strings.ToLower(location), "file:") {
+		return location
+	}
+	u, err := url.Parse(location)
+	if nil != err {
+		return location
+	}
+	path := u.Path
+	// windows urls look like file:///C:/Music/...
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+
+}
+
+// pathToFileURL converts a local file path into a file:// url
+func pathToFileURL(path string) string {
+
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Host: "localhost", Path: path}
+	return u.String()
+
+}
+
+// itspFile returns the path of a file that this tool keeps next to
+// the given library, eg: Library.xml -> Library.itsp.cache
+func itspFile(libraryPath, suffix string) string {
+	ext := path.Ext(libraryPath)
+	baseName := libraryPath[0 : len(libraryPath)-len(ext)]
+	return fmt.Sprintf("%s.itsp.%s", baseName, suffix)
+}
+
 func artist(track *spotify.FullTrack) string {
 	artistStr := ""
 	for i, artist := range track.Artists {
@@ -0,0 +1,164 @@
+package main
+
+import (
+	re "regexp"
+	"strconv"
+	"strings"
+)
+
+// VersionInfo is what a track or album title says about which version
+// of a recording it is, eg: a live version or a remix
+type VersionInfo struct {
+	Live         bool
+	Acoustic     bool
+	Demo         bool
+	Extended     bool
+	RadioEdit    bool
+	Instrumental bool
+	Karaoke      bool
+	Cover        bool
+	Mono         bool
+	Stereo       bool
+
+	Remix   bool
+	Remixer string
+
+	Remaster     bool
+	RemasterYear int
+}
+
+// defaultVersionPenalties are added to a title comparison when only
+// one of the titles is the named version. Versions that are different
+// recordings are never a match, while a remaster barely differs
+var defaultVersionPenalties = map[string]float64{
+	"live":          1,
+	"karaoke":       1,
+	"instrumental":  1,
+	"cover":         1,
+	"remix":         0.6,
+	"remixer":       0.4,
+	"acoustic":      0.6,
+	"demo":          0.6,
+	"extended":      0.3,
+	"radio edit":    0.1,
+	"mono":          0.1,
+	"stereo":        0.05,
+	"remaster":      0,
+	"remaster year": 0.02,
+}
+
+// versionPenalty is the penalty of the profile for a version
+// mismatch, falling back to the default when it has none
+func (mp *MatchProfile) versionPenalty(name string) float64 {
+	if penalty, ok := mp.VersionPenalties[name]; ok {
+		return penalty
+	}
+	return defaultVersionPenalties[name]
+}
+
+var (
+	liveRe         = re.MustCompile(`(^|[^a-z])live([^a-z]|$)`)
+	acousticRe     = re.MustCompile(`\b(acoustic|unplugged)\b`)
+	demoRe         = re.MustCompile(`\bdemo\b`)
+	extendedRe     = re.MustCompile(`\bextended\b`)
+	radioEditRe    = re.MustCompile(`\bradio (edit|version|mix)\b`)
+	instrumentalRe = re.MustCompile(`instrumental`)
+	karaokeRe      = re.MustCompile(`karaoke`)
+	coverRe        = re.MustCompile(`\bcover\b`)
+	monoRe         = re.MustCompile(`\bmono\b`)
+	stereoRe       = re.MustCompile(`\bstereo\b`)
+	remixRe        = re.MustCompile(`\bremix(ed)?\b`)
+	remasterRe     = re.MustCompile(`\bremaster(ed)?\b`)
+
+	// the remixer is named before the remix, eg: (tiesto remix),
+	// or after it, eg: - remixed by tiesto
+	remixerRe   = re.MustCompile(`(?:^|[(\[]|\s-\s)([^()\[\]]+?)\s+remix\b`)
+	remixedByRe = re.MustCompile(`\bremixed by ([^()\[\]]+)`)
+
+	remasterYearRe = re.MustCompile(
+		`\b((?:19|20)[0-9]{2})\s+(?:digital\s+)?remaster|remaster(?:ed)?\s+(?:version\s+)?(?:in\s+)?((?:19|20)[0-9]{2})\b`)
+
+	// words that describe the remix rather than the remixer
+	remixWords = re.MustCompile(`\b(extended|radio|club|original|official|edit|version|vocal|dub)\b`)
+)
+
+// ClassifyVersion tags the given title with the versions it names
+func ClassifyVersion(title string) VersionInfo {
+
+	s := strings.ToLower(title)
+	vi := VersionInfo{
+		Live:         liveRe.MatchString(s),
+		Acoustic:     acousticRe.MatchString(s),
+		Demo:         demoRe.MatchString(s),
+		Extended:     extendedRe.MatchString(s),
+		RadioEdit:    radioEditRe.MatchString(s),
+		Instrumental: instrumentalRe.MatchString(s),
+		Karaoke:      karaokeRe.MatchString(s),
+		Cover:        coverRe.MatchString(s),
+		Mono:         monoRe.MatchString(s),
+		Stereo:       stereoRe.MatchString(s),
+		Remix:        remixRe.MatchString(s),
+		Remaster:     remasterRe.MatchString(s),
+	}
+
+	if vi.Remix {
+		var remixer string
+		if groups := remixedByRe.FindStringSubmatch(s); len(groups) > 0 {
+			remixer = groups[1]
+		} else if groups := remixerRe.FindStringSubmatch(s); len(groups) > 0 {
+			remixer = groups[1]
+		}
+		vi.Remixer = strings.Join(strings.Fields(remixWords.ReplaceAllString(remixer, "")), " ")
+	}
+
+	if groups := remasterYearRe.FindStringSubmatch(s); len(groups) > 0 {
+		year := groups[1]
+		if year == "" {
+			year = groups[2]
+		}
+		vi.RemasterYear, _ = strconv.Atoi(year)
+	}
+
+	return vi
+
+}
+
+// CompareVersions totals the penalties of the versions that only one
+// of a and b is, returning the names of those versions
+func CompareVersions(a, b VersionInfo) (float64, []string) {
+
+	var penalty float64
+	var names []string
+	mismatch := func(name string, differ bool) {
+		// a penalty of zero is no mismatch at all
+		if !differ || activeProfile.versionPenalty(name) <= 0 {
+			return
+		}
+		penalty += activeProfile.versionPenalty(name)
+		names = append(names, name)
+	}
+
+	mismatch("live", a.Live != b.Live)
+	mismatch("karaoke", a.Karaoke != b.Karaoke)
+	mismatch("instrumental", a.Instrumental != b.Instrumental)
+	mismatch("cover", a.Cover != b.Cover)
+	mismatch("remix", a.Remix != b.Remix)
+	mismatch("remixer", a.Remixer != "" && b.Remixer != "" && a.Remixer != b.Remixer)
+	mismatch("acoustic", a.Acoustic != b.Acoustic)
+	mismatch("demo", a.Demo != b.Demo)
+	mismatch("extended", a.Extended != b.Extended)
+	mismatch("radio edit", a.RadioEdit != b.RadioEdit)
+	mismatch("mono", a.Mono != b.Mono)
+	mismatch("stereo", a.Stereo != b.Stereo)
+	mismatch("remaster", a.Remaster != b.Remaster)
+	mismatch("remaster year", a.RemasterYear != 0 && b.RemasterYear != 0 && a.RemasterYear != b.RemasterYear)
+
+	return penalty, names
+
+}
+
+// versionMismatch compares the versions that the two names are
+func versionMismatch(a, b string) (float64, string) {
+	penalty, names := CompareVersions(ClassifyVersion(a), ClassifyVersion(b))
+	return penalty, strings.Join(names, ", ")
+}
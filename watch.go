@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// Watcher keeps a library synced with spotify for as long as it is
+// running, by polling the library file and syncing after it changes
+type Watcher struct {
+
+	// watch settings
+	Interval time.Duration
+	Debounce time.Duration
+
+	// runtime
+	syncer  *Syncer
+	path    string
+	program *SimpleCommandProgram
+}
+
+// NewWatcher creates a new watcher for the command program and library
+// that are supplied, asking for the sync settings to use on each change.
+// It returns nil if the library cannot be watched
+func NewWatcher(program *SimpleCommandProgram, lib *Library) *Watcher {
+
+	switch lib.Format {
+	case FormatFolder, FormatCSV:
+		// these ask how to read them every time they are read
+		program.Errorf("watching is not supported for %s libraries", lib.Format)
+		return nil
+	}
+
+	// nobody is around to answer while watching,
+	// so uncertain matches always wait for review
+	mapper := newTrackMapper(program, lib)
+	mapper.DeferReview = true
+
+	return &Watcher{
+		Interval: 2 * time.Second,
+		Debounce: 5 * time.Second,
+
+		syncer:  newSyncer(program, lib, mapper),
+		path:    lib.LibraryFile,
+		program: program,
+	}
+
+}
+
+// Run this watcher until the program is stopped
+func (w *Watcher) Run() {
+
+	modified, err := w.modTime()
+	if nil != err {
+		w.program.Errorf("Error watching %s: %s", w.path, err)
+		return
+	}
+
+	w.program.Log("running initial sync...")
+	w.syncer.Run()
+
+	for cycle := 1; ; cycle++ {
+
+		w.program.Logf("watching %s for changes...", w.path)
+		modified = w.waitForChange(modified)
+
+		w.program.Logf("cycle %d: library changed at %s, syncing...",
+			cycle, modified.Format(time.Kitchen))
+		start := time.Now()
+
+		lib, err := ReadLibrary(w.program, w.path)
+		if nil != err {
+			// itunes may still be writing, the next write will retry
+			w.program.Warningf("cycle %d: cannot read library: %s", cycle, err)
+			continue
+		}
+		w.syncer.SetLibrary(lib)
+		w.syncer.Run()
+
+		w.program.Logf("cycle %d: done in %s", cycle, time.Since(start).Round(time.Second))
+
+	}
+
+}
+
+// waitForChange polls until the library file has been modified after
+// the given time and then left alone for the debounce period, since
+// itunes rewrites the whole file a number of times in a row
+func (w *Watcher) waitForChange(since time.Time) time.Time {
+
+	changed := time.Time{}
+	last := since
+	for {
+
+		time.Sleep(w.Interval)
+
+		modified, err := w.modTime()
+		if nil != err {
+			// the file is briefly missing while being replaced
+			continue
+		}
+
+		if modified.After(last) {
+			last = modified
+			changed = time.Now()
+			continue
+		}
+
+		if !changed.IsZero() && time.Since(changed) >= w.Debounce {
+			return last
+		}
+
+	}
+
+}
+
+func (w *Watcher) modTime() (time.Time, error) {
+
+	stat, err := os.Stat(w.path)
+	if nil != err {
+		return time.Time{}, err
+	}
+	return stat.ModTime(), nil
+
+}
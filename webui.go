@@ -0,0 +1,465 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	itunes "github.com/rydrman/go-itunes-library"
+	"github.com/zmb3/spotify"
+)
+
+// ReviewServer serves a web page on the local session server for
+// reviewing uncertain matches from the match cache, where each decision
+// is written straight back into the cache
+type ReviewServer struct {
+	items  []*webReviewItem
+	byID   map[string]*webReviewItem
+	mapper *Importer
+	lock   sync.Mutex
+
+	// runtime
+	lib     *Library
+	program *SimpleCommandProgram
+}
+
+// webReviewItem is a library track waiting for review
+type webReviewItem struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Artist    string   `json:"artist"`
+	Album     string   `json:"album"`
+	Playlists []string `json:"playlists"`
+	Match     string   `json:"match"`
+	SpotifyID string   `json:"spotifyID"`
+	Score     float64  `json:"score"`
+	Resolved  bool     `json:"resolved"`
+
+	track      *itunes.Track
+	candidates map[string]*spotify.FullTrack
+}
+
+// webCandidate is a spotify track offered as a match for an item
+type webCandidate struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Artists    string  `json:"artists"`
+	Album      string  `json:"album"`
+	AlbumType  string  `json:"albumType"`
+	Duration   int     `json:"duration"`
+	Popularity int     `json:"popularity"`
+	Score      float64 `json:"score"`
+	Breakdown  string  `json:"breakdown"`
+	Preview    string  `json:"preview"`
+	URL        string  `json:"url"`
+}
+
+// NewReviewServer creates a new review server for the command program
+// and library, queueing every cached match that was not certain
+func NewReviewServer(program *SimpleCommandProgram, lib *Library) *ReviewServer {
+
+	rs := &ReviewServer{
+		byID: make(map[string]*webReviewItem),
+		mapper: &Importer{
+			matchCache: InitMatchCache(lib.LibraryFile),
+			decisions:  InitDecisionLog(lib.LibraryFile),
+			lib:        lib,
+			program:    program,
+		},
+		lib:     lib,
+		program: program,
+	}
+
+	rs.mapper.askMatchSettings(program)
+
+	playlists := make(map[string][]string)
+	for _, iList := range lib.Playlists {
+		if rs.mapper.shouldSkipPlaylist(iList) {
+			continue
+		}
+		for _, track := range iList.PlaylistItems {
+			names := playlists[track.PersistentID]
+			if !StringInSlice(iList.Name, names) {
+				playlists[track.PersistentID] = append(names, iList.Name)
+			}
+		}
+	}
+
+	for _, track := range lib.Tracks {
+		cached, ok := rs.mapper.matchCache.TrackMap[track.PersistentID]
+		if !ok || (cached.SpotifyID != "" && cached.Score <= thresholdMatched) {
+			continue
+		}
+		item := &webReviewItem{
+			ID:        track.PersistentID,
+			Name:      track.Name,
+			Artist:    track.Artist,
+			Album:     track.Album,
+			Playlists: playlists[track.PersistentID],
+			Match:     cached.SpotifyTrack,
+			SpotifyID: cached.SpotifyID,
+			Score:     cached.Score,
+			track:     track,
+		}
+		rs.items = append(rs.items, item)
+		rs.byID[item.ID] = item
+	}
+
+	// the least certain matches are the most worth looking at
+	sort.SliceStable(rs.items, func(a, b int) bool {
+		if (rs.items[a].SpotifyID == "") != (rs.items[b].SpotifyID == "") {
+			return rs.items[a].SpotifyID != ""
+		}
+		return rs.items[a].Score > rs.items[b].Score
+	})
+
+	return rs
+
+}
+
+// Run serves the review page until the user is done with it
+func (rs *ReviewServer) Run() {
+
+	if len(rs.items) == 0 {
+		rs.program.Log("there are no uncertain matches to review")
+		return
+	}
+
+	Session.Handle("/review", http.HandlerFunc(rs.servePage))
+	Session.Handle("/review/items", http.HandlerFunc(rs.serveItems))
+	Session.Handle("/review/candidates", http.HandlerFunc(rs.serveCandidates))
+	Session.Handle("/review/decide", http.HandlerFunc(rs.serveDecide))
+
+	url := Session.LocalURL("/review")
+	rs.program.Logf("%d matches to review at %s", len(rs.items), url)
+	openBrowser(url)
+
+	rs.program.Log("press enter when you are done reviewing:")
+	_ = rs.program.CaptureInput()
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.mapper.matchCache.SaveCache()
+
+}
+
+func (rs *ReviewServer) servePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(reviewPage))
+}
+
+// serveItems lists the queued items, filtered by the playlist,
+// artist, min and max score query parameters
+func (rs *ReviewServer) serveItems(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	playlist := query.Get("playlist")
+	artistFilter := strings.ToLower(query.Get("artist"))
+	minScore, err := strconv.ParseFloat(query.Get("min"), 64)
+	if nil != err {
+		minScore = -1
+	}
+	maxScore, err := strconv.ParseFloat(query.Get("max"), 64)
+	if nil != err {
+		maxScore = 1e9
+	}
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	items := []*webReviewItem{}
+	for _, item := range rs.items {
+		if playlist != "" && !StringInSlice(playlist, item.Playlists) {
+			continue
+		}
+		if artistFilter != "" && !strings.Contains(strings.ToLower(item.Artist), artistFilter) {
+			continue
+		}
+		if item.Score < minScore || item.Score > maxScore {
+			continue
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, items)
+
+}
+
+// serveCandidates searches for the candidates of an item, using the
+// usual search attempts or the custom search given as q
+func (rs *ReviewServer) serveCandidates(w http.ResponseWriter, r *http.Request) {
+
+	// the item and cache are changed by decisions, so copy
+	// what is needed before searching without the lock
+	rs.lock.Lock()
+	item, ok := rs.byID[r.URL.Query().Get("id")]
+	var spotifyID string
+	cached := make(TrackMap)
+	if ok {
+		spotifyID = item.SpotifyID
+		if match, found := rs.mapper.matchCache.TrackMap[item.track.PersistentID]; found {
+			entry := *match
+			cached[item.track.PersistentID] = &entry
+		}
+	}
+	rs.lock.Unlock()
+	if !ok {
+		http.Error(w, "unknown track", http.StatusNotFound)
+		return
+	}
+
+	goal := PreprocessTrackArtists(item.track)
+	queries := SearchAttempts(goal)
+	if custom := r.URL.Query().Get("q"); custom != "" {
+		queries = []SearchQuery{{Strategy: "custom", Query: custom, Pages: 1}}
+	} else if len(queries) > 3 {
+		queries = queries[:3]
+	}
+
+	var results []spotify.FullTrack
+	seen := make(map[spotify.ID]bool)
+	if spotifyID != "" && r.URL.Query().Get("q") == "" {
+		if current := cached.GetMatch(item.track); nil != current && current.Valid() {
+			results = append(results, *current.spotify)
+			seen[current.spotify.ID] = true
+		}
+	}
+	for _, query := range queries {
+		for _, res := range Session.SearchTracks(query.Query, 1) {
+			if !seen[res.ID] {
+				results = append(results, res)
+				seen[res.ID] = true
+			}
+		}
+	}
+
+	scored := rs.mapper.scoreTracks(results, goal)
+	sort.Sort(byScoreAndDate(scored))
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	item.candidates = make(map[string]*spotify.FullTrack)
+	candidates := []*webCandidate{}
+	for _, mt := range scored {
+		id := mt.spotify.ID.String()
+		item.candidates[id] = mt.spotify
+		candidates = append(candidates, &webCandidate{
+			ID:         id,
+			Name:       mt.spotify.Name,
+			Artists:    artist(mt.spotify),
+			Album:      mt.spotify.Album.Name,
+			AlbumType:  mt.spotify.Album.AlbumType,
+			Duration:   mt.spotify.Duration,
+			Popularity: mt.spotify.Popularity,
+			Score:      mt.score,
+			Breakdown:  mt.breakdown.String(),
+			Preview:    mt.spotify.PreviewURL,
+			URL:        "https://open.spotify.com/track/" + id,
+		})
+	}
+	writeJSON(w, candidates)
+
+}
+
+// serveDecide stores the chosen candidate for an item in the match
+// cache, where an empty spotify id marks the track as unavailable
+func (rs *ReviewServer) serveDecide(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "expected POST", http.StatusMethodNotAllowed)
+		return
+	}
+	// a json body cannot be sent cross site without a preflight,
+	// unlike the plain text of a form
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var decision struct {
+		ID        string `json:"id"`
+		SpotifyID string `json:"spotifyID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&decision); nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	item, ok := rs.byID[decision.ID]
+	if !ok {
+		http.Error(w, "unknown track", http.StatusNotFound)
+		return
+	}
+
+	mt := &MatchedTrack{itunes: item.track, score: -1}
+	if decision.SpotifyID != "" {
+		test, ok := item.candidates[decision.SpotifyID]
+		if !ok {
+			http.Error(w, "unknown candidate", http.StatusBadRequest)
+			return
+		}
+		mt.spotify = test
+		mt.breakdown = rs.mapper.compareTrack(PreprocessTrackArtists(item.track), test, false)
+		mt.score = mt.breakdown.Total()
+	}
+
+	// the candidates are scored the same way as during the import
+	goal := PreprocessTrackArtists(item.track)
+	var candidates []*MatchedTrack
+	for _, test := range item.candidates {
+		sb := rs.mapper.compareTrack(goal, test, false)
+		candidates = append(candidates, &MatchedTrack{
+			itunes:    item.track,
+			spotify:   test,
+			score:     sb.Total(),
+			breakdown: sb,
+		})
+	}
+	if err := rs.mapper.decisions.Log(goal, mt, candidates); nil != err {
+		rs.program.Warningf("error logging decision: %s", err)
+	}
+
+	rs.mapper.matchCache.TrackMap.Store(mt)
+	if err := rs.mapper.matchCache.SaveCache(); nil != err {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	item.Match = SpotifyCacheString(mt.spotify)
+	item.SpotifyID = decision.SpotifyID
+	item.Score = mt.score
+	item.Resolved = true
+	rs.program.Logf("reviewed %s -> %s", ItunesCacheString(item.track), item.Match)
+	writeJSON(w, item)
+
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+// reviewPage is the single page review app, which talks
+// to the handlers above to list, search and decide
+const reviewPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>iTunes to Spotify - Review</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+#queue { width: 40%; overflow-y: auto; border-right: 1px solid #ccc; }
+#detail { flex: 1; overflow-y: auto; padding: 1em; }
+#filters { padding: 0.5em; background: #f4f4f4; position: sticky; top: 0; }
+#filters input { width: 8em; }
+.item { padding: 0.5em; border-bottom: 1px solid #eee; cursor: pointer; }
+.item:hover, .item.active { background: #e8f4ff; }
+.item.resolved { opacity: 0.5; }
+.muted { color: #888; font-size: 0.9em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: 0.3em; border-bottom: 1px solid #eee; }
+audio { height: 2em; }
+</style>
+</head>
+<body>
+<div id="queue">
+  <div id="filters">
+    <input id="playlist" placeholder="playlist">
+    <input id="artist" placeholder="artist">
+    <input id="min" placeholder="min score" size="5">
+    <input id="max" placeholder="max score" size="5">
+    <button onclick="loadItems()">filter</button>
+  </div>
+  <div id="items"></div>
+</div>
+<div id="detail"><p class="muted">select a track to review</p></div>
+<script>
+var current = null;
+
+function esc(s) {
+  var d = document.createElement('div');
+  d.textContent = s == null ? '' : String(s);
+  return d.innerHTML;
+}
+
+function loadItems() {
+  var params = ['playlist', 'artist', 'min', 'max'].map(function (k) {
+    return k + '=' + encodeURIComponent(document.getElementById(k).value);
+  }).join('&');
+  fetch('/review/items?' + params).then(function (r) { return r.json(); }).then(function (items) {
+    var list = document.getElementById('items');
+    list.innerHTML = '';
+    items.forEach(function (item) {
+      var div = document.createElement('div');
+      div.className = 'item' + (item.resolved ? ' resolved' : '');
+      div.innerHTML = '<b>' + esc(item.name) + '</b> - ' + esc(item.artist) +
+        '<div class="muted">' + esc(item.album) + ' | ' + esc(item.match) +
+        (item.spotifyID ? ' [' + item.score.toFixed(4) + ']' : '') + '</div>';
+      div.onclick = function () { showItem(item, div); };
+      list.appendChild(div);
+    });
+  });
+}
+
+function showItem(item, div, query) {
+  current = item;
+  document.querySelectorAll('.item.active').forEach(function (e) { e.classList.remove('active'); });
+  if (div) { div.classList.add('active'); current.div = div; }
+  var detail = document.getElementById('detail');
+  detail.innerHTML = '<h2>' + esc(item.name) + '</h2>' +
+    '<p>' + esc(item.artist) + ' - ' + esc(item.album) + '</p>' +
+    '<p class="muted">playlists: ' + esc((item.playlists || []).join(', ')) + '</p>' +
+    '<p><input id="query" placeholder="custom search" value="' + esc(query || '') + '">' +
+    ' <button onclick="search()">search</button>' +
+    ' <button onclick="decide(\'\')">unavailable on spotify</button></p>' +
+    '<p class="muted">searching...</p>';
+  var url = '/review/candidates?id=' + encodeURIComponent(item.id);
+  if (query) { url += '&q=' + encodeURIComponent(query); }
+  fetch(url).then(function (r) { return r.json(); }).then(function (candidates) {
+    var rows = candidates.map(function (c) {
+      var secs = Math.round(c.duration / 1000);
+      return '<tr><td><button onclick="decide(\'' + c.id + '\')">choose</button></td>' +
+        '<td title="' + esc(c.breakdown) + '">' + c.score.toFixed(4) + '</td>' +
+        '<td><a href="' + esc(c.url) + '" target="_blank">' + esc(c.name) + '</a></td>' +
+        '<td>' + esc(c.artists) + '</td>' +
+        '<td>' + esc(c.album) + ' <span class="muted">' + esc(c.albumType) + '</span></td>' +
+        '<td>' + Math.floor(secs / 60) + ':' + ('0' + secs % 60).slice(-2) + '</td>' +
+        '<td>' + c.popularity + '</td>' +
+        '<td>' + (c.preview ? '<audio controls preload="none" src="' + esc(c.preview) + '"></audio>' : '') + '</td></tr>';
+    }).join('');
+    detail.removeChild(detail.lastChild);
+    detail.insertAdjacentHTML('beforeend', candidates.length == 0 ? '<p>no candidates found</p>' :
+      '<table><tr><th></th><th>score</th><th>title</th><th>artists</th><th>album</th>' +
+      '<th>time</th><th>pop</th><th>preview</th></tr>' + rows + '</table>');
+  });
+}
+
+function search() {
+  showItem(current, current.div, document.getElementById('query').value);
+}
+
+function decide(spotifyID) {
+  fetch('/review/decide', {
+    method: 'POST',
+    headers: {'Content-Type': 'application/json'},
+    body: JSON.stringify({id: current.id, spotifyID: spotifyID})
+  }).then(function (r) { return r.json(); }).then(function (item) {
+    current.div.classList.add('resolved');
+    current.div.querySelector('.muted').textContent = item.album + ' | ' + item.match;
+    var next = current.div.nextSibling;
+    if (next) { next.click(); }
+  });
+}
+
+loadItems();
+</script>
+</body>
+</html>
+`